@@ -0,0 +1,130 @@
+package jwalk
+
+import "math"
+
+// GetTyped finds the first entry in d with the given key and asserts its value
+// to T. It returns the zero value and false if the key is absent or the value
+// is not a T.
+//
+// Example:
+//
+//	created, ok := jwalk.GetTyped[time.Time](doc, "created")
+func GetTyped[T any](d Document, key string) (T, bool) {
+	v, ok := d.lookup(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return AsTyped[T](v)
+}
+
+// AsTyped asserts v to T without panicking. It returns the zero value and false
+// on mismatch. It is useful for Array elements:
+//
+//	for _, elem := range arr {
+//	    if t, ok := jwalk.AsTyped[time.Time](elem); ok {
+//	        // ...
+//	    }
+//	}
+func AsTyped[T any](v any) (T, bool) {
+	t, ok := v.(T)
+	return t, ok
+}
+
+// Int returns the value of the first entry with the given key as an int64.
+// It accepts Go integer values, float64 values (as numbers decode) that are
+// whole and within int64 range, and number types such as json.Number that
+// provide an Int64 method. It returns false if the key is absent or the value
+// does not convert exactly.
+func (d Document) Int(key string) (int64, bool) {
+	v, ok := d.lookup(key)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return uintToInt(uint64(n))
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return uintToInt(n)
+	case float32:
+		return floatToInt(float64(n))
+	case float64:
+		return floatToInt(n)
+	case interface{ Int64() (int64, error) }:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// uintToInt converts u to an int64 if it is in range.
+func uintToInt(u uint64) (int64, bool) {
+	if u > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(u), true
+}
+
+// floatToInt converts f to an int64 if it is whole and in range.
+func floatToInt(f float64) (int64, bool) {
+	// -2^63 is exactly representable; 2^63 is the first float above MaxInt64
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// Float returns the value of the first entry with the given key as a float64.
+// It accepts Go floating-point and integer values, and number types such as
+// json.Number that provide a Float64 method. It returns false if the key is
+// absent or the value is not a number.
+func (d Document) Float(key string) (float64, bool) {
+	v, ok := d.lookup(key)
+	if !ok {
+		return 0, false
+	}
+	if n, ok := v.(interface{ Float64() (float64, error) }); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return toFloat(v)
+}
+
+// Str returns the value of the first entry with the given key if it is a
+// string. (It is not named String, which renders the Document; see
+// Document.String.)
+func (d Document) Str(key string) (string, bool) {
+	return GetTyped[string](d, key)
+}
+
+// Bool returns the value of the first entry with the given key if it is a
+// bool.
+func (d Document) Bool(key string) (bool, bool) {
+	return GetTyped[bool](d, key)
+}
+
+// lookup returns the value of the first entry with the given key.
+func (d Document) lookup(key string) (any, bool) {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
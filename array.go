@@ -0,0 +1,221 @@
+package jwalk
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// FlattenOne returns a new Array in which every element that is itself an
+// Array is replaced by its elements, one level deep. Non-Array elements
+// (including Documents) are passed through unchanged.
+//
+//	Array{1, Array{2, Array{3}}, 4}.FlattenOne() // Array{1, 2, Array{3}, 4}
+func (a Array) FlattenOne() Array {
+	out := make(Array, 0, len(a))
+	for _, elem := range a {
+		if inner, ok := elem.(Array); ok {
+			out = append(out, inner...)
+		} else {
+			out = append(out, elem)
+		}
+	}
+	return out
+}
+
+// All returns an iterator over the indexes and elements of a, in order, as
+// slices.All does.
+func (a Array) All() iter.Seq2[int, any] {
+	return slices.All(a)
+}
+
+// ForEach calls fn for each element of a in order, with its index, stopping
+// early if fn returns false.
+func (a Array) ForEach(fn func(i int, value any) bool) {
+	for i, elem := range a {
+		if !fn(i, elem) {
+			return
+		}
+	}
+}
+
+// Chunk splits a into consecutive sub-arrays of size elements; the final chunk
+// may be shorter. The chunks share a's storage but are capacity-clipped, so
+// appending to one never overwrites its neighbour. It panics if size is less
+// than 1.
+func (a Array) Chunk(size int) []Array {
+	if size < 1 {
+		panic("jwalk: Chunk size must be at least 1")
+	}
+	chunks := make([]Array, 0, (len(a)+size-1)/size)
+	for i := 0; i < len(a); i += size {
+		end := min(i+size, len(a))
+		chunks = append(chunks, a[i:end:end])
+	}
+	return chunks
+}
+
+// Sort sorts a in place using less, which reports whether element x must sort
+// before element y. The sort is stable, so elements that neither sorts before
+// keep their relative order, keeping output deterministic.
+//
+//	a.Sort(func(x, y any) bool { return x.(float64) < y.(float64) })
+func (a Array) Sort(less func(x, y any) bool) {
+	slices.SortStableFunc(a, func(x, y any) int {
+		switch {
+		case less(x, y):
+			return -1
+		case less(y, x):
+			return 1
+		}
+		return 0
+	})
+}
+
+// SortStrings sorts the string elements of a in place in increasing byte-wise
+// order. Any elements that are not strings are moved after the strings,
+// keeping their relative order.
+func (a Array) SortStrings() {
+	slices.SortStableFunc(a, func(x, y any) int {
+		xs, xok := x.(string)
+		ys, yok := y.(string)
+		switch {
+		case xok && yok:
+			return strings.Compare(xs, ys)
+		case xok:
+			return -1
+		case yok:
+			return 1
+		}
+		return 0
+	})
+}
+
+// IndexBy returns a Document indexing the records in a by their key field:
+// one entry per element, in order, keyed by the element's string value for
+// key and holding the element itself, e.g.
+//
+//	users.IndexBy("id") // [{"id":"u1",...},{"id":"u2",...}] -> {"u1":{...},"u2":{...}}
+//
+// Every element must be a Document with a string value for key, and no two
+// may share that value; otherwise IndexBy returns an error naming the first
+// offending element.
+func (a Array) IndexBy(key string) (Document, error) {
+	out := make(Document, 0, len(a))
+	seen := make(map[string]int, len(a))
+	for i, elem := range a {
+		d, ok := elem.(Document)
+		if !ok {
+			return nil, fmt.Errorf("element %d is %T, not a Document", i, elem)
+		}
+		v, ok := d.lookup(key)
+		if !ok {
+			return nil, fmt.Errorf("element %d has no key %q", i, key)
+		}
+		k, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("element %d key %q is %T, not a string", i, key, v)
+		}
+		if j, dup := seen[k]; dup {
+			return nil, fmt.Errorf("element %d duplicates %s %q of element %d", i, key, k, j)
+		}
+		seen[k] = i
+		out = append(out, Entry{Key: k, Value: d})
+	}
+	return out, nil
+}
+
+// Contains reports whether a has an element equal to v. See IndexOf for the
+// comparison used.
+//
+//	enabled.Contains("metrics")
+func (a Array) Contains(v any) bool {
+	return a.IndexOf(v) >= 0
+}
+
+// IndexOf returns the index of the first element of a equal to v, or -1.
+//
+// Documents are equal if they have equal entries in the same order, and
+// Arrays if they have equal elements in the same order. Numbers of any
+// built-in Go numeric type compare by value, so IndexOf(1) finds a decoded 1
+// (a float64); as usual for floats, NaN equals nothing, not even itself. A nil
+// v matches only untyped nil elements (JSON null), not nil Documents or
+// Arrays. Other values are compared with reflect.DeepEqual.
+func (a Array) IndexOf(v any) int {
+	for i, elem := range a {
+		if valuesEqual(elem, v) {
+			return i
+		}
+	}
+	return -1
+}
+
+// valuesEqual reports whether x and y are equal as described by IndexOf.
+func valuesEqual(x, y any) bool {
+	switch xv := x.(type) {
+	case nil:
+		return y == nil
+	case Document:
+		yv, ok := y.(Document)
+		if !ok || len(xv) != len(yv) || (xv == nil) != (yv == nil) {
+			return false
+		}
+		for i := range xv {
+			if xv[i].Key != yv[i].Key || !valuesEqual(xv[i].Value, yv[i].Value) {
+				return false
+			}
+		}
+		return true
+	case Array:
+		yv, ok := y.(Array)
+		if !ok || len(xv) != len(yv) || (xv == nil) != (yv == nil) {
+			return false
+		}
+		for i := range xv {
+			if !valuesEqual(xv[i], yv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if xf, ok := toFloat(x); ok {
+		yf, ok := toFloat(y)
+		return ok && xf == yf
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// toFloat converts a value of a built-in numeric type to float64. Named types
+// such as time.Duration are not numbers for this purpose.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,122 @@
+package jwalk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// WithBOMStripping makes Registry.Decode and DecodeStream accept input that
+// starts with a byte order mark, as written by some Windows editors. A UTF-8
+// BOM is dropped; a UTF-16 BOM, big- or little-endian, is dropped and the rest
+// of the input transcoded to UTF-8 as it is read. Input without a BOM is read
+// as UTF-8, as usual. Without this option a BOM is a syntax error, as JSON
+// (RFC 8259) forbids one.
+func WithBOMStripping() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.BOMStripping = true
+		return nil
+	}
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+)
+
+// inputReader returns the reader to decode rd from: rd itself, or under
+// WithBOMStripping a reader past any byte order mark that yields UTF-8.
+func (r *Registry) inputReader(rd io.Reader) (io.Reader, error) {
+	if !r.bomStripping {
+		return rd, nil
+	}
+	br := bufio.NewReader(rd)
+	head, err := br.Peek(len(bomUTF8))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		br.Discard(len(bomUTF8))
+		return br, nil
+	case bytes.HasPrefix(head, bomUTF16BE):
+		br.Discard(len(bomUTF16BE))
+		return &utf16Reader{r: br, order: binary.BigEndian}, nil
+	case bytes.HasPrefix(head, bomUTF16LE):
+		br.Discard(len(bomUTF16LE))
+		return &utf16Reader{r: br, order: binary.LittleEndian}, nil
+	default:
+		return br, nil
+	}
+}
+
+// utf16Reader transcodes UTF-16 input to UTF-8.
+type utf16Reader struct {
+	r       *bufio.Reader
+	order   binary.ByteOrder
+	pending []byte // encoded but not yet returned
+	err     error  // sticky error from r or from decoding
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(u.pending) == 0 {
+			// once some output is ready, do not block waiting for more
+			if u.err != nil || (n > 0 && u.r.Buffered() < 2) {
+				break
+			}
+			c, err := u.readRune()
+			if err != nil {
+				u.err = err
+				break
+			}
+			u.pending = utf8.AppendRune(u.pending[:0], c)
+		}
+		m := copy(p[n:], u.pending)
+		u.pending = u.pending[m:]
+		n += m
+	}
+	if n == 0 && len(p) > 0 {
+		return 0, u.err
+	}
+	return n, nil
+}
+
+// readRune decodes the next character, which is one code unit or a surrogate
+// pair.
+func (u *utf16Reader) readRune() (rune, error) {
+	c, err := u.readUnit()
+	if err != nil {
+		return 0, err
+	}
+	if !utf16.IsSurrogate(c) {
+		return c, nil
+	}
+	c2, err := u.readUnit()
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if r := utf16.DecodeRune(c, c2); err == nil && r != utf8.RuneError {
+		return r, nil
+	}
+	return 0, errors.New("invalid UTF-16 input: unpaired surrogate")
+}
+
+// readUnit reads the next UTF-16 code unit.
+func (u *utf16Reader) readUnit() (rune, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("invalid UTF-16 input: odd number of bytes")
+		}
+		return 0, err
+	}
+	return rune(u.order.Uint16(b[:])), nil
+}
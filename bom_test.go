@@ -0,0 +1,73 @@
+package jwalk_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+
+	"github.com/calumari/jwalk"
+)
+
+// encodeUTF16 encodes s as UTF-16 in the given byte order, after a byte order
+// mark.
+func encodeUTF16(order binary.AppendByteOrder, s string) []byte {
+	b := order.AppendUint16(nil, 0xFEFF)
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = order.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestBOMStripping(t *testing.T) {
+	const in = "{\"name\": \"café \U0001F600\", \"n\": [1]}"
+	const want = "{\"name\":\"café \U0001F600\",\"n\":[1]}"
+	for _, tt := range []struct {
+		name    string
+		strip   bool
+		in      []byte
+		wantErr string
+	}{
+		{"no BOM", true, []byte(in), ""},
+		{"UTF-8", true, append([]byte("\xEF\xBB\xBF"), in...), ""},
+		{"UTF-16BE", true, encodeUTF16(binary.BigEndian, in), ""},
+		{"UTF-16LE", true, encodeUTF16(binary.LittleEndian, in), ""},
+		{"without the option", false, append([]byte("\xEF\xBB\xBF"), in...), "invalid character"},
+		{"UTF-16 without the option", false, encodeUTF16(binary.LittleEndian, in), "invalid character"},
+		{"BOM only", true, []byte("\xEF\xBB\xBF"), "EOF"},
+		{"odd length", true, append(encodeUTF16(binary.BigEndian, in), '\n'), "invalid UTF-16 input: odd number of bytes"},
+		{"unpaired surrogate", true, append(encodeUTF16(binary.BigEndian, `{"a": "`), 0xD8, 0x00, 0, '"', 0, '}'), "invalid UTF-16 input: unpaired surrogate"},
+		{"truncated surrogate pair", true, append(encodeUTF16(binary.LittleEndian, `{"a": "`), 0x00, 0xD8), "invalid UTF-16 input: unpaired surrogate"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []jwalk.Option
+			if tt.strip {
+				opts = append(opts, jwalk.WithBOMStripping())
+			}
+			reg, err := jwalk.NewRegistry(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// reading a byte at a time splits code units and surrogate pairs
+			for _, rd := range []io.Reader{bytes.NewReader(tt.in), iotest.OneByteReader(bytes.NewReader(tt.in))} {
+				var doc jwalk.Document
+				err := reg.Decode(rd, &doc)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("Decode = %v, %v, want error containing %q", doc, err, tt.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := reg.Marshal(doc); err != nil || string(got) != want {
+					t.Fatalf("Decode = %s (%v), want %s", got, err, want)
+				}
+			}
+		})
+	}
+}
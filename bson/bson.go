@@ -0,0 +1,106 @@
+// Package bson converts between jwalk's ordered types and the MongoDB driver's
+// bson.D / bson.E / bson.A, which share the same ordered-document model.
+//
+// It is a module of its own, github.com/calumari/jwalk/bson, so the core
+// jwalk module does not depend on the MongoDB driver.
+package bson
+
+import (
+	"sort"
+
+	"github.com/calumari/jwalk"
+	mbson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ToBSON converts d into a bson.D, recursively converting nested Document and
+// Array values. Other values (including directive results such as time.Time)
+// are passed through unchanged for the driver to encode.
+func ToBSON(d jwalk.Document) mbson.D {
+	if d == nil {
+		return nil
+	}
+	out := make(mbson.D, len(d))
+	for i, e := range d {
+		out[i] = mbson.E{Key: e.Key, Value: toBSONValue(e.Value)}
+	}
+	return out
+}
+
+// ToBSONArray converts a into a bson.A. See ToBSON.
+func ToBSONArray(a jwalk.Array) mbson.A {
+	if a == nil {
+		return nil
+	}
+	out := make(mbson.A, len(a))
+	for i, v := range a {
+		out[i] = toBSONValue(v)
+	}
+	return out
+}
+
+func toBSONValue(v any) any {
+	switch val := v.(type) {
+	case jwalk.Document:
+		return ToBSON(val)
+	case jwalk.Array:
+		return ToBSONArray(val)
+	default:
+		return val
+	}
+}
+
+// FromBSON converts d into a Document, recursively converting nested bson.D,
+// bson.M and bson.A values. bson.M has no defined order, so its keys are
+// sorted to keep the result deterministic. bson.DateTime values become
+// time.Time in UTC; other values are passed through unchanged.
+func FromBSON(d mbson.D) jwalk.Document {
+	if d == nil {
+		return nil
+	}
+	out := make(jwalk.Document, len(d))
+	for i, e := range d {
+		out[i] = jwalk.Entry{Key: e.Key, Value: fromBSONValue(e.Value)}
+	}
+	return out
+}
+
+// FromBSONArray converts a into an Array. See FromBSON.
+func FromBSONArray(a mbson.A) jwalk.Array {
+	if a == nil {
+		return nil
+	}
+	out := make(jwalk.Array, len(a))
+	for i, v := range a {
+		out[i] = fromBSONValue(v)
+	}
+	return out
+}
+
+func fromBSONValue(v any) any {
+	switch val := v.(type) {
+	case mbson.D:
+		return FromBSON(val)
+	case mbson.M:
+		return fromBSONMap(val)
+	case mbson.A:
+		return FromBSONArray(val)
+	case mbson.DateTime:
+		return val.Time().UTC()
+	default:
+		return val
+	}
+}
+
+func fromBSONMap(m mbson.M) jwalk.Document {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(jwalk.Document, len(keys))
+	for i, k := range keys {
+		out[i] = jwalk.Entry{Key: k, Value: fromBSONValue(m[k])}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package bson_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calumari/jwalk"
+	"github.com/calumari/jwalk/bson"
+	mbson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var created = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func TestToBSON(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   jwalk.Document
+		want mbson.D
+	}{
+		{"nil", nil, nil},
+		{"empty", jwalk.Document{}, mbson.D{}},
+		{"ordered", jwalk.Document{{Key: "b", Value: 1.0}, {Key: "a", Value: "x"}}, mbson.D{{Key: "b", Value: 1.0}, {Key: "a", Value: "x"}}},
+		{"nested",
+			jwalk.Document{{Key: "d", Value: jwalk.Document{{Key: "x", Value: jwalk.Array{true, nil, jwalk.Document{}}}}}},
+			mbson.D{{Key: "d", Value: mbson.D{{Key: "x", Value: mbson.A{true, nil, mbson.D{}}}}}}},
+		{"directive results", jwalk.Document{{Key: "t", Value: created}, {Key: "a", Value: jwalk.Array(nil)}},
+			mbson.D{{Key: "t", Value: created}, {Key: "a", Value: mbson.A(nil)}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bson.ToBSON(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ToBSON = %#v, want %#v", got, tt.want)
+			}
+			if back := bson.FromBSON(got); !reflect.DeepEqual(back, tt.in) {
+				t.Errorf("FromBSON(ToBSON(in)) = %#v, want %#v", back, tt.in)
+			}
+		})
+	}
+}
+
+func TestFromBSON(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   mbson.D
+		want jwalk.Document
+	}{
+		{"nil", nil, nil},
+		{"ordered", mbson.D{{Key: "b", Value: int32(1)}, {Key: "a", Value: "x"}}, jwalk.Document{{Key: "b", Value: int32(1)}, {Key: "a", Value: "x"}}},
+		{"map sorted", mbson.D{{Key: "m", Value: mbson.M{"z": 1, "a": mbson.A{mbson.M{"y": 2, "b": 3}}}}},
+			jwalk.Document{{Key: "m", Value: jwalk.Document{
+				{Key: "a", Value: jwalk.Array{jwalk.Document{{Key: "b", Value: 3}, {Key: "y", Value: 2}}}},
+				{Key: "z", Value: 1},
+			}}}},
+		{"date time", mbson.D{{Key: "t", Value: mbson.DateTime(created.UnixMilli())}}, jwalk.Document{{Key: "t", Value: created}}},
+		{"array", mbson.D{{Key: "a", Value: mbson.A{mbson.D{{Key: "x", Value: nil}}, mbson.A{}}}},
+			jwalk.Document{{Key: "a", Value: jwalk.Array{jwalk.Document{{Key: "x", Value: nil}}, jwalk.Array{}}}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bson.FromBSON(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("FromBSON = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDecodeToBSON converts documents decoded by a Registry, as when loading
+// JSON into MongoDB.
+func TestDecodeToBSON(t *testing.T) {
+	reg, err := jwalk.NewRegistry(jwalk.WithStdlib())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		in      string
+		want    mbson.D
+		wantErr string
+	}{
+		{"directives", `{"name": "x", "created": {"$std.time": "2023-10-01T12:00:00Z"}, "tags": ["a", {"k": 1}]}`,
+			mbson.D{{Key: "name", Value: "x"}, {Key: "created", Value: created}, {Key: "tags", Value: mbson.A{"a", mbson.D{{Key: "k", Value: 1.0}}}}}, ""},
+		{"directive error", `{"created": {"$std.time": "yesterday"}}`, nil, `created: directive "std.time": parsing time "yesterday"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc jwalk.Document
+			err := reg.Unmarshal([]byte(tt.in), &doc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Unmarshal error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bson.ToBSON(doc); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ToBSON = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/calumari/jwalk/bson
+
+go 1.25.0
+
+require (
+	github.com/calumari/jwalk v0.1.0
+	go.mongodb.org/mongo-driver/v2 v2.9.1
+)
+
+require github.com/go-json-experiment/json v0.0.0-20250813233538-9b1f9ea2e11b // indirect
@@ -0,0 +1,72 @@
+package jwalk
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Pair constructs an Entry from a key and value.
+func Pair(k string, v any) Entry {
+	return Entry{Key: k, Value: v}
+}
+
+// DocumentBuilder builds a Document entry by entry, preserving insertion
+// order.
+//
+// Example:
+//
+//	doc := jwalk.NewDocument().
+//	    Add("name", "example").
+//	    Add("port", 8080).
+//	    Build()
+type DocumentBuilder struct {
+	doc    Document
+	unique bool
+	seen   map[string]struct{}
+	err    error
+}
+
+// NewDocument returns an empty DocumentBuilder.
+func NewDocument() *DocumentBuilder {
+	return &DocumentBuilder{doc: Document{}}
+}
+
+// UniqueKeys makes the builder reject duplicate keys. A duplicate Add is
+// ignored and recorded as an error, reported by Err.
+func (b *DocumentBuilder) UniqueKeys() *DocumentBuilder {
+	b.unique = true
+	if b.seen == nil {
+		b.seen = make(map[string]struct{}, len(b.doc))
+		for _, e := range b.doc {
+			b.seen[e.Key] = struct{}{}
+		}
+	}
+	return b
+}
+
+// Add appends an entry with the given key and value.
+func (b *DocumentBuilder) Add(key string, value any) *DocumentBuilder {
+	if b.unique {
+		if _, dup := b.seen[key]; dup {
+			if b.err == nil {
+				b.err = fmt.Errorf("duplicate key %q", key)
+			}
+			return b
+		}
+		b.seen[key] = struct{}{}
+	}
+	b.doc = append(b.doc, Entry{Key: key, Value: value})
+	return b
+}
+
+// Build returns the built Document. The builder may continue to be used; later
+// additions do not affect previously built documents.
+func (b *DocumentBuilder) Build() Document {
+	return slices.Clone(b.doc)
+}
+
+// Err returns the first error recorded by the builder (e.g. a duplicate key
+// under UniqueKeys), or nil.
+func (b *DocumentBuilder) Err() error {
+	return b.err
+}
@@ -0,0 +1,31 @@
+package jwalk
+
+import (
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// canonicalMarshalers encodes trees for Canonical independently of any
+// Registry's settings.
+var canonicalMarshalers = Marshalers(newRegistry())
+
+// Canonical encodes root (a decoded tree, or any value the json package can
+// encode) as canonical JSON following the JSON Canonicalization Scheme (RFC
+// 8785): object keys sorted, no insignificant whitespace, numbers in their
+// shortest ECMAScript form and strings with minimal escaping. Documents that
+// differ only in key order produce identical bytes, making the output suitable
+// for hashing and signing.
+//
+// As RFC 8785 requires, numbers are treated as IEEE 754 doubles, so integers
+// beyond ±2^53 lose precision. A Document with duplicate keys is an error.
+func Canonical(root any) ([]byte, error) {
+	b, err := json.Marshal(root, json.WithMarshalers(canonicalMarshalers))
+	if err != nil {
+		return nil, err
+	}
+	v := jsontext.Value(b)
+	if err := v.Canonicalize(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,46 @@
+package jwalk_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestCanonical(t *testing.T) {
+	tenth := 0.1 // not a constant, so that 0.1 + 0.2 is inexact
+	for _, tt := range []struct {
+		name    string
+		in      any
+		want    string
+		wantErr string
+	}{
+		{"keys sorted", jwalk.Document{{Key: "b", Value: 1.0}, {Key: "a", Value: jwalk.Document{{Key: "d", Value: nil}, {Key: "c", Value: true}}}},
+			`{"a":{"c":true,"d":null},"b":1}`, ""},
+		{"key order by UTF-16", jwalk.Document{{Key: "\U0001F600", Value: 1.0}, {Key: "\uFB33", Value: 2.0}, {Key: "a", Value: 3.0}},
+			"{\"a\":3,\"\U0001F600\":1,\"\uFB33\":2}", ""},
+		{"numbers", jwalk.Array{1.0, -0.0, 1e21, 1e-7, tenth + 0.2, int64(1) << 53},
+			`[1,0,1e+21,1e-7,0.30000000000000004,9007199254740992]`, ""},
+		{"strings", jwalk.Array{"\u00e9\t\"\\</\u2028\u001f"}, `["é\t\"\\</` + "\u2028" + `\u001f"]`, ""},
+		{"plain values", map[string]any{"z": []any{}, "y": map[string]any{}}, `{"y":{},"z":[]}`, ""},
+		{"empty", jwalk.Document{}, `{}`, ""},
+		{"nil", jwalk.Document(nil), `null`, ""},
+		{"duplicate keys", jwalk.Document{{Key: "a", Value: 1.0}, {Key: "a", Value: 2.0}}, "", `duplicate object member name "a"`},
+		{"NaN", jwalk.Array{math.NaN()}, "", "unsupported value: NaN"},
+		{"unencodable", jwalk.Array{func() {}}, "", "cannot marshal from Go func()"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jwalk.Canonical(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Canonical = %s, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("Canonical = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+// Package cbor decodes and encodes CBOR (RFC 8949) using jwalk's ordered
+// Document/Array model and directive Registry.
+//
+// CBOR maps decode as jwalk.Document with their encoded key order preserved,
+// and map keys prefixed with "$" are dispatched through the Registry's
+// directives exactly as they are for JSON input.
+package cbor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/calumari/jwalk"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// maxNesting bounds container nesting when transcoding.
+const maxNesting = 10000
+
+// Unmarshal decodes CBOR data into out using reg's unmarshalers.
+//
+// The CBOR item is transcoded to JSON text and then decoded with
+// reg.Unmarshal. Byte strings become base64 (standard encoding) strings, tags
+// are dropped in favour of their content, undefined becomes null, and integer
+// map keys use their decimal form. Other key types, and floats with no JSON
+// representation (NaN, ±Inf), produce an error.
+func Unmarshal(data []byte, out any, reg *jwalk.Registry) error {
+	in, err := ToJSON(data)
+	if err != nil {
+		return err
+	}
+	return reg.Unmarshal(in, out)
+}
+
+// Marshal encodes v as CBOR using reg's marshalers, preserving the order of
+// Document entries in the emitted maps.
+//
+// Containers use definite lengths. Numbers that encode in JSON as integers,
+// with no fraction or exponent, encode as CBOR integers when they fit in 64
+// bits; all others, including 1.0, 1e3 and -0, encode as float64.
+func Marshal(v any, reg *jwalk.Registry) ([]byte, error) {
+	in, err := reg.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(in)
+}
+
+// ToJSON transcodes a single CBOR data item into JSON text.
+func ToJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := jsontext.NewEncoder(&buf)
+	t := &transcoder{data: data}
+	if err := t.item(enc, 0); err != nil {
+		return nil, err
+	}
+	if t.off != len(data) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes after data item", len(data)-t.off)
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// FromJSON transcodes a single JSON value into a CBOR data item.
+func FromJSON(in []byte) ([]byte, error) {
+	dec := jsontext.NewDecoder(bytes.NewReader(in))
+	out, err := appendJSON(nil, dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.ReadToken(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+		return nil, fmt.Errorf("cbor: %w", err)
+	}
+	return out, nil
+}
+
+// CBOR major types.
+const (
+	majorUint   = 0
+	majorNegint = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// indefinite is the additional-information value marking an
+// indefinite-length item; breakByte terminates one.
+const (
+	indefinite = 31
+	breakByte  = 0xff
+)
+
+// transcoder reads CBOR items from data and writes equivalent JSON tokens.
+type transcoder struct {
+	data []byte
+	off  int
+}
+
+var errUnexpectedEOF = errors.New("cbor: unexpected end of data")
+
+// head reads an item head, returning its major type, additional information
+// and argument.
+func (t *transcoder) head() (major, info byte, arg uint64, err error) {
+	if t.off >= len(t.data) {
+		return 0, 0, 0, errUnexpectedEOF
+	}
+	b := t.data[t.off]
+	t.off++
+	major, info = b>>5, b&0x1f
+
+	var n int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	case info == indefinite:
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: invalid additional information %d at offset %d", info, t.off-1)
+	}
+	if t.off+n > len(t.data) {
+		return 0, 0, 0, errUnexpectedEOF
+	}
+	for _, c := range t.data[t.off : t.off+n] {
+		arg = arg<<8 | uint64(c)
+	}
+	t.off += n
+	return major, info, arg, nil
+}
+
+// atBreak consumes a break byte if one is next.
+func (t *transcoder) atBreak() (bool, error) {
+	if t.off >= len(t.data) {
+		return false, errUnexpectedEOF
+	}
+	if t.data[t.off] == breakByte {
+		t.off++
+		return true, nil
+	}
+	return false, nil
+}
+
+// bytes reads a byte or text string body, joining indefinite-length chunks.
+func (t *transcoder) bytes(major, info byte, arg uint64) ([]byte, error) {
+	if info != indefinite {
+		if arg > uint64(len(t.data)-t.off) {
+			return nil, errUnexpectedEOF
+		}
+		b := t.data[t.off : t.off+int(arg)]
+		t.off += int(arg)
+		return b, nil
+	}
+
+	var out []byte
+	for {
+		done, err := t.atBreak()
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return out, nil
+		}
+		m, i, a, err := t.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || i == indefinite {
+			return nil, fmt.Errorf("cbor: invalid chunk in indefinite-length string at offset %d", t.off)
+		}
+		chunk, err := t.bytes(m, i, a)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+}
+
+// item transcodes one data item.
+func (t *transcoder) item(enc *jsontext.Encoder, depth int) error {
+	if depth > maxNesting {
+		return errors.New("cbor: exceeded max nesting depth")
+	}
+	major, info, arg, err := t.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case majorUint:
+		return enc.WriteToken(jsontext.Uint(arg))
+
+	case majorNegint:
+		if arg > math.MaxInt64 {
+			return enc.WriteToken(jsontext.Float(-1 - float64(arg)))
+		}
+		return enc.WriteToken(jsontext.Int(-1 - int64(arg)))
+
+	case majorBytes:
+		b, err := t.bytes(major, info, arg)
+		if err != nil {
+			return err
+		}
+		return enc.WriteToken(jsontext.String(base64.StdEncoding.EncodeToString(b)))
+
+	case majorText:
+		b, err := t.bytes(major, info, arg)
+		if err != nil {
+			return err
+		}
+		return enc.WriteToken(jsontext.String(string(b)))
+
+	case majorArray:
+		if err := enc.WriteToken(jsontext.BeginArray); err != nil {
+			return err
+		}
+		for i := uint64(0); info == indefinite || i < arg; i++ {
+			if info == indefinite {
+				done, err := t.atBreak()
+				if err != nil {
+					return err
+				}
+				if done {
+					break
+				}
+			}
+			if err := t.item(enc, depth+1); err != nil {
+				return err
+			}
+		}
+		return enc.WriteToken(jsontext.EndArray)
+
+	case majorMap:
+		if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+			return err
+		}
+		for i := uint64(0); info == indefinite || i < arg; i++ {
+			if info == indefinite {
+				done, err := t.atBreak()
+				if err != nil {
+					return err
+				}
+				if done {
+					break
+				}
+			}
+			key, err := t.key()
+			if err != nil {
+				return err
+			}
+			if err := enc.WriteToken(jsontext.String(key)); err != nil {
+				return fmt.Errorf("cbor: map key %q: %w", key, err)
+			}
+			if err := t.item(enc, depth+1); err != nil {
+				return err
+			}
+		}
+		return enc.WriteToken(jsontext.EndObject)
+
+	case majorTag:
+		return t.item(enc, depth+1)
+
+	default: // majorSimple
+		return t.simple(enc, info, arg)
+	}
+}
+
+// key reads a map key, which must be a text string or an integer.
+func (t *transcoder) key() (string, error) {
+	major, info, arg, err := t.head()
+	if err != nil {
+		return "", err
+	}
+	switch major {
+	case majorText:
+		b, err := t.bytes(major, info, arg)
+		return string(b), err
+	case majorUint:
+		return strconv.FormatUint(arg, 10), nil
+	case majorNegint:
+		if arg > math.MaxInt64 {
+			return "", fmt.Errorf("cbor: map key out of range at offset %d", t.off)
+		}
+		return strconv.FormatInt(-1-int64(arg), 10), nil
+	default:
+		return "", fmt.Errorf("cbor: unsupported map key major type %d at offset %d", major, t.off)
+	}
+}
+
+// simple transcodes a major type 7 item (simple values and floats).
+func (t *transcoder) simple(enc *jsontext.Encoder, info byte, arg uint64) error {
+	var f float64
+	switch info {
+	case 20:
+		return enc.WriteToken(jsontext.False)
+	case 21:
+		return enc.WriteToken(jsontext.True)
+	case 22, 23: // null, undefined
+		return enc.WriteToken(jsontext.Null)
+	case 25:
+		f = float16(uint16(arg))
+	case 26:
+		f = float64(math.Float32frombits(uint32(arg)))
+	case 27:
+		f = math.Float64frombits(arg)
+	default:
+		return fmt.Errorf("cbor: unsupported simple value %d at offset %d", arg, t.off)
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("cbor: float %v has no JSON representation", f)
+	}
+	return enc.WriteToken(jsontext.Float(f))
+}
+
+// float16 converts an IEEE 754 half-precision value to float64.
+func float16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(frac+1024, exp-25)
+	}
+}
+
+// appendHead appends an item head with the given major type and argument,
+// using the shortest encoding.
+func appendHead(out []byte, major byte, arg uint64) []byte {
+	m := major << 5
+	switch {
+	case arg < 24:
+		return append(out, m|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(out, m|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(out, m|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(out, m|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(out, m|27), arg)
+	}
+}
+
+// appendJSON reads one JSON value from dec and appends its CBOR encoding.
+func appendJSON(out []byte, dec *jsontext.Decoder) ([]byte, error) {
+	tok, err := dec.ReadToken()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.Kind() {
+	case 'n':
+		return append(out, majorSimple<<5|22), nil
+	case 'f':
+		return append(out, majorSimple<<5|20), nil
+	case 't':
+		return append(out, majorSimple<<5|21), nil
+	case '"':
+		s := tok.String()
+		return append(appendHead(out, majorText, uint64(len(s))), s...), nil
+	case '0':
+		return appendNumber(out, tok), nil
+
+	case '[':
+		var body []byte
+		var n uint64
+		for dec.PeekKind() != ']' {
+			if body, err = appendJSON(body, dec); err != nil {
+				return nil, err
+			}
+			n++
+		}
+		if _, err := dec.ReadToken(); err != nil { // ']'
+			return nil, err
+		}
+		return append(appendHead(out, majorArray, n), body...), nil
+
+	case '{':
+		var body []byte
+		var n uint64
+		for dec.PeekKind() != '}' {
+			// key
+			if body, err = appendJSON(body, dec); err != nil {
+				return nil, err
+			}
+			// value
+			if body, err = appendJSON(body, dec); err != nil {
+				return nil, err
+			}
+			n++
+		}
+		if _, err := dec.ReadToken(); err != nil { // '}'
+			return nil, err
+		}
+		return append(appendHead(out, majorMap, n), body...), nil
+
+	default:
+		return nil, fmt.Errorf("cbor: unexpected JSON token %v", tok.Kind())
+	}
+}
+
+// appendNumber encodes a JSON number as a CBOR integer when it is written as
+// an integer that fits in 64 bits, or as a float64 otherwise. -0 is a float64,
+// since CBOR integers have no negative zero.
+func appendNumber(out []byte, tok jsontext.Token) []byte {
+	s := tok.String()
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return appendHead(out, majorUint, u)
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil && i < 0 {
+		return appendHead(out, majorNegint, uint64(-1-i))
+	}
+	out = append(out, majorSimple<<5|27)
+	return binary.BigEndian.AppendUint64(out, math.Float64bits(tok.Float()))
+}
@@ -0,0 +1,73 @@
+package cbor_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/calumari/jwalk"
+	"github.com/calumari/jwalk/cbor"
+)
+
+func TestMarshalNumbers(t *testing.T) {
+	reg, err := jwalk.NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in   float64
+		want string // encoded item, hex
+	}{
+		{0, "00"},
+		{1, "01"},
+		{-1, "20"},
+		{1e3, "1903e8"},
+		{math.Copysign(0, -1), "fb8000000000000000"},
+		{1.5, "fb3ff8000000000000"},
+		{-1.5, "fbbff8000000000000"},
+	}
+	for _, tt := range tests {
+		b, err := cbor.Marshal(jwalk.Array{tt.in}, reg)
+		if err != nil {
+			t.Fatalf("Marshal(%v): %v", tt.in, err)
+		}
+		if got := hex.EncodeToString(b[1:]); got != tt.want {
+			t.Errorf("Marshal(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+
+		var v any
+		if err := cbor.Unmarshal(b, &v, reg); err != nil {
+			t.Fatalf("Unmarshal(Marshal(%v)): %v", tt.in, err)
+		}
+		got := v.(jwalk.Array)[0].(float64)
+		if got != tt.in || math.Signbit(got) != math.Signbit(tt.in) {
+			t.Errorf("round trip of %v = %v", tt.in, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	reg, err := jwalk.NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := []byte(`{"b":1,"a":[true,null,"x",-2,2.5],"c":{"z":{},"y":[]}}`)
+	var doc jwalk.Document
+	if err := reg.Unmarshal(in, &doc); err != nil {
+		t.Fatal(err)
+	}
+	b, err := cbor.Marshal(doc, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cbor.ToJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, in) {
+		t.Errorf("ToJSON(Marshal(%s)) = %s", in, got)
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/calumari/jwalk/cbor
+
+go 1.25.0
+
+require (
+	github.com/calumari/jwalk v0.1.0
+	github.com/go-json-experiment/json v0.0.0-20250813233538-9b1f9ea2e11b
+)
@@ -0,0 +1,80 @@
+package jwalk
+
+import (
+	"errors"
+	"strings"
+)
+
+// WithErrorCollection makes the Registry's decode methods (Unmarshal, Decode,
+// DecodeValue and the UnmarshalWith* variants) carry on past recoverable
+// errors, e.g. to report every problem in a config file in one pass. A
+// recoverable error is one that leaves the input readable after the failing
+// value:
+//
+//   - a failing directive, including a failing Validator; its sentinel
+//     object decodes as nil
+//   - a WithValueTransform function error; the value decodes as nil
+//
+// Each is recorded with its path and decoding continues. The errors are
+// returned together as DecodeErrors once the input has been read, so the
+// decoded value is complete apart from the failed parts. Any other error,
+// such as a syntax error, a duplicate object key (which the json package
+// rejects as it reads the input) or an exceeded limit, still stops the
+// decode; it is returned joined with the errors collected before it.
+//
+// Errors are collected only through the Registry's methods; decoding with
+// json.Unmarshal and the Registry's Options stops at the first error.
+func WithErrorCollection() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.ErrorCollection = true
+		return nil
+	}
+}
+
+// DecodeErrors holds the errors collected by a decode under
+// WithErrorCollection, in the order they were found.
+type DecodeErrors []*PathError
+
+// Error renders one error per line.
+func (e DecodeErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, pe := range e {
+		msgs[i] = pe.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e DecodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, pe := range e {
+		errs[i] = pe
+	}
+	return errs
+}
+
+// collect records err, a failure that left the decoder past the failing value,
+// if errors are being collected (WithErrorCollection), and reports whether it
+// did; the caller then carries on as if the value were null.
+func (st *decodeState) collect(err error) bool {
+	if !st.collecting {
+		return false
+	}
+	var pe *PathError
+	if !errors.As(err, &pe) {
+		pe = &PathError{Err: err}
+	}
+	st.errs = append(st.errs, pe)
+	return true
+}
+
+// collected combines the errors collected during a decode with err, the error
+// that ended it, if any.
+func (st *decodeState) collected(err error) error {
+	if len(st.errs) == 0 {
+		return err
+	}
+	if err == nil {
+		return DecodeErrors(st.errs)
+	}
+	return errors.Join(DecodeErrors(st.errs), err)
+}
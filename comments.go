@@ -0,0 +1,133 @@
+package jwalk
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/go-json-experiment/json"
+)
+
+// EntryComments holds the comments attached to a single Document entry.
+type EntryComments struct {
+	Leading  []string // comments on the lines before the entry's key
+	Trailing []string // comments after the entry's key on the same line
+}
+
+// Comments maps the JSON Pointer (RFC 6901) of each commented Document entry
+// to its comments. Comment text includes its delimiters ("// ..." or
+// "/* ... */") so it can be written back verbatim.
+type Comments map[string]EntryComments
+
+// UnmarshalWithComments decodes in like Unmarshal, first accepting and
+// removing "//" line comments and "/* */" block comments, and reports the
+// comments attached to each decoded Document entry.
+//
+// A comment that starts on the same line as an entry's key, after it, trails
+// that entry; any other comment leads the next entry key in the input.
+// Comments that fit neither rule, such as one after the last entry of the
+// input, are not reported.
+//
+// The comments are captured as metadata only; the marshalers do not emit
+// them.
+func (r *Registry) UnmarshalWithComments(in []byte, out any, opts ...json.Options) (Comments, error) {
+	stripped, found, err := stripComments(in)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := r.UnmarshalWithPositions(stripped, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		ptr string
+		Position
+	}
+	entries := make([]entry, 0, len(pos))
+	for ptr, p := range pos {
+		entries = append(entries, entry{ptr, p})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.Offset, b.Offset) })
+
+	comments := make(Comments)
+	for _, c := range found {
+		// last entry key before the comment
+		i, _ := slices.BinarySearchFunc(entries, c.start, func(e entry, off int64) int { return cmp.Compare(e.Offset, off) })
+		i--
+		if i >= 0 && entries[i].Line == c.line {
+			ec := comments[entries[i].ptr]
+			ec.Trailing = append(ec.Trailing, c.text)
+			comments[entries[i].ptr] = ec
+			continue
+		}
+		if i+1 < len(entries) {
+			ec := comments[entries[i+1].ptr]
+			ec.Leading = append(ec.Leading, c.text)
+			comments[entries[i+1].ptr] = ec
+		}
+	}
+	return comments, nil
+}
+
+// sourceComment is a comment found by stripComments.
+type sourceComment struct {
+	start int64 // byte offset of the opening delimiter
+	line  int   // line of the opening delimiter, starting at 1
+	text  string
+}
+
+// stripComments returns a copy of in with every comment outside strings
+// replaced by spaces (newlines are kept, so offsets and line numbers are
+// unchanged), along with the comments found, in input order.
+func stripComments(in []byte) ([]byte, []sourceComment, error) {
+	out := slices.Clone(in)
+	var found []sourceComment
+	line := 1
+	inString := false
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		switch {
+		case c == '\n':
+			line++
+		case inString:
+			switch c {
+			case '\\':
+				i++ // skip the escaped byte
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(out) && (out[i+1] == '/' || out[i+1] == '*'):
+			start, startLine := i, line
+			if out[i+1] == '/' {
+				for i < len(out) && out[i] != '\n' {
+					i++
+				}
+			} else {
+				end := -1
+				for j := i + 2; j+1 < len(out); j++ {
+					if out[j] == '*' && out[j+1] == '/' {
+						end = j + 2
+						break
+					}
+				}
+				if end < 0 {
+					return nil, nil, errors.New("unterminated block comment")
+				}
+				i = end
+			}
+			found = append(found, sourceComment{start: int64(start), line: startLine, text: string(in[start:i])})
+			for j := start; j < i; j++ {
+				if out[j] == '\n' {
+					line++
+				} else {
+					out[j] = ' '
+				}
+			}
+			i-- // revisit the byte after the comment (e.g. its newline)
+		}
+	}
+	return out, found, nil
+}
@@ -0,0 +1,59 @@
+package jwalk_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestUnmarshalWithComments(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		in       string
+		want     jwalk.Comments
+		wantJSON string
+		wantErr  string
+	}{
+		{"none", `{"a": 1}`, jwalk.Comments{}, `{"a":1}`, ""},
+		{"leading and trailing", "{\n\t// the name\n\t\"name\": \"x\", // trailing\n\t\"n\": 1 /* one */\n}",
+			jwalk.Comments{
+				"/name": {Leading: []string{"// the name"}, Trailing: []string{"// trailing"}},
+				"/n":    {Trailing: []string{"/* one */"}},
+			}, `{"name":"x","n":1}`, ""},
+		{"several leading", "{\n/* block\n   comment */\n// line\n\"a\": 1}",
+			jwalk.Comments{"/a": {Leading: []string{"/* block\n   comment */", "// line"}}}, `{"a":1}`, ""},
+		{"nested", "{\"cfg\": {\n\t\"x\": [1, {\n\t\t// why\n\t\t\"y\": true\n\t}]\n}}",
+			jwalk.Comments{"/cfg/x/1/y": {Leading: []string{"// why"}}}, `{"cfg":{"x":[1,{"y":true}]}}`, ""},
+		{"in strings", `{"url": "http://x/*y*/", "q": "\"//"}`, jwalk.Comments{}, `{"url":"http://x/*y*/","q":"\"//"}`, ""},
+		{"after the last entry", "{\"a\": 1\n// dropped\n}", jwalk.Comments{}, `{"a":1}`, ""},
+		{"without newline", `{"a": 1} // end`, jwalk.Comments{"/a": {Trailing: []string{"// end"}}}, `{"a":1}`, ""},
+		{"unterminated", "{\"a\": 1 /* open\n}", nil, "", "unterminated block comment"},
+		{"invalid", "{\"a\": // no value\n}", nil, "", "missing value after object name"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc jwalk.Document
+			got, err := reg.UnmarshalWithComments([]byte(tt.in), &doc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UnmarshalWithComments = %v, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalWithComments = %q, want %q", got, tt.want)
+			}
+			if b, err := reg.Marshal(doc); err != nil || string(b) != tt.wantJSON {
+				t.Errorf("decoded %s (%v), want %s", b, err, tt.wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package jwalk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// StdCronDirective constructs a Directive that decodes values of the form:
+//
+//	{"$std.cron": "0 */5 * * *"}
+//
+// into a Schedule, so malformed schedules fail at load time rather than when
+// they are first used. The expression has the five standard cron fields:
+// minute (0-59), hour (0-23), day of month (1-31), month (1-12 or jan-dec)
+// and day of week (0-7 or sun-sat, with 0 and 7 both Sunday). Each field is
+// "*" or a comma-separated list of values, ranges ("1-5") and steps ("*/15",
+// "10-30/5", "5/10"). The shorthands @yearly (or @annually), @monthly,
+// @weekly, @daily (or @midnight) and @hourly are also accepted. An expression
+// with a bad field count or an out-of-range value produces an error, as does
+// one that can never match, such as "0 0 31 2 *".
+var StdCronDirective = NewDirective("std.cron", unmarshalSchedule)
+
+// Schedule is a parsed cron expression produced by StdCronDirective. The zero
+// Schedule never matches.
+type Schedule struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64 // bit n set if value n matches
+	domStar, dowStar              bool   // day fields given as "*", and so unrestricted
+}
+
+// String returns the expression s was parsed from.
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the earliest time after after, to the minute, that s matches,
+// in after's location. As in cron, when both the day of month and the day of
+// week are restricted, a day matching either one matches. A time skipped by a
+// daylight saving change never matches, and one repeated by it may match
+// twice. Next returns the zero Time if s never matches.
+func (s Schedule) Next(after time.Time) time.Time {
+	if s.minute == 0 {
+		return time.Time{}
+	}
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, loc).Add(time.Minute)
+
+	// every valid schedule matches within 8 years (29 February, across a
+	// non-leap century year)
+	for limit := t.Year() + 9; t.Year() <= limit; {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = dayStart(t.Year(), t.Month()+1, 1, loc)
+		case !s.dayMatches(t):
+			t = dayStart(t.Year(), t.Month(), t.Day()+1, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayStart returns the first instant of the given day in loc. time.Date moves
+// a midnight that falls in a daylight saving gap back to the previous day, so
+// the day then starts after the gap instead.
+func dayStart(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	noon := time.Date(year, month, day, 12, 0, 0, 0, loc)
+	for t.Day() != noon.Day() {
+		t = t.Add(time.Hour)
+	}
+	return t
+}
+
+// dayMatches reports whether the day of t matches the day of month and day of
+// week fields.
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// cronShorthands maps the accepted "@" shorthands to their expressions.
+var cronShorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes one field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names of the values from min, if any
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseSchedule parses a cron expression. See StdCronDirective.
+func ParseSchedule(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if full, ok := cronShorthands[strings.ToLower(spec)]; ok {
+		spec = full
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return Schedule{}, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	s := Schedule{expr: expr}
+	for i, dst := range []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow} {
+		bits, err := cronFields[i].parse(fields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		*dst = bits
+	}
+	if s.dow&(1<<7) != 0 { // 7 is Sunday too
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+
+	if s.dowStar && !s.anyDayOfMonthExists() {
+		return Schedule{}, fmt.Errorf("invalid cron expression %q: day of month never occurs in the given months", expr)
+	}
+	return s, nil
+}
+
+// anyDayOfMonthExists reports whether some month in s has one of its days of
+// the month.
+func (s Schedule) anyDayOfMonthExists() bool {
+	for m := time.January; m <= time.December; m++ {
+		if s.month&(1<<uint(m)) == 0 {
+			continue
+		}
+		days := time.Date(2000, m+1, 0, 0, 0, 0, 0, time.UTC).Day() // 2000 is a leap year
+		if s.dom&(1<<uint(days+1)-1) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parse returns the set of values matched by the field text s.
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(s, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+
+		var lo, hi int
+		var err error
+		if rng == "*" {
+			lo, hi = f.min, f.max
+		} else if from, to, ok := strings.Cut(rng, "-"); ok {
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(to); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s range %q is backwards", f.name, rng)
+			}
+		} else {
+			if lo, err = f.value(rng); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if hasStep { // "5/10" runs from 5 to the maximum
+				hi = f.max
+			}
+		}
+
+		step := 1
+		if hasStep {
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad %s step %q", f.name, stepText)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single field value, given as a number or, for fields with
+// names, a case-insensitive name.
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s value %q", f.name, s)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s value %d out of range %d-%d", f.name, n, f.min, f.max)
+	}
+	return n, nil
+}
+
+func unmarshalSchedule(dec *jsontext.Decoder) (Schedule, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return Schedule{}, err
+	}
+	return ParseSchedule(s)
+}
@@ -0,0 +1,96 @@
+package jwalk_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a Monday
+	after := time.Date(2024, 1, 15, 10, 7, 30, 0, time.UTC)
+	for _, tt := range []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"* * * * *", after, time.Date(2024, 1, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", after, time.Date(2024, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", after, time.Date(2024, 1, 15, 10, 25, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", after, time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"30 8 * * mon-fri", after, time.Date(2024, 1, 16, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", after, time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 JAN,jul *", after, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", after, time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC)}, // either day field
+		{"0 0 29 2 *", after, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 1", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)}, // a Monday in February
+		{"@hourly", after, time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@yearly", after, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", after, time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		// 02:30 does not occur on 10 March 2024 in New York
+		{"30 2 * * *", time.Date(2024, 3, 9, 12, 0, 0, 0, newYork), time.Date(2024, 3, 11, 2, 30, 0, 0, newYork)},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := jwalk.ParseSchedule(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tt.after); !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+				t.Fatalf("Next(%v) = %v, want %v", tt.after, got, tt.want)
+			}
+			if s.String() != tt.expr {
+				t.Errorf("String = %q, want %q", s.String(), tt.expr)
+			}
+		})
+	}
+
+	if got := (jwalk.Schedule{}).Next(after); !got.IsZero() {
+		t.Errorf("zero Schedule: Next = %v, want the zero Time", got)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, tt := range []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "expected 5 fields, got 0"},
+		{"* * * *", "expected 5 fields, got 4"},
+		{"@reboot", "expected 5 fields, got 1"},
+		{"60 * * * *", "minute value 60 out of range 0-59"},
+		{"* 24 * * *", "hour value 24 out of range 0-23"},
+		{"* * 0 * *", "day of month value 0 out of range 1-31"},
+		{"* * * 13 *", "month value 13 out of range 1-12"},
+		{"* * * * 8", "day of week value 8 out of range 0-7"},
+		{"* * * foo *", `bad month value "foo"`},
+		{"5-1 * * * *", `minute range "5-1" is backwards`},
+		{"*/0 * * * *", `bad minute step "0"`},
+		{"*/x * * * *", `bad minute step "x"`},
+		{"1,,2 * * * *", `bad minute value ""`},
+		{"0 0 31 2 *", "day of month never occurs in the given months"},
+		{"0 0 31 apr,jun *", "day of month never occurs in the given months"},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := jwalk.ParseSchedule(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseSchedule = %v, %v, want error containing %q", s, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStdCronDirective(t *testing.T) {
+	testStdlib(t, []stdlibTest{
+		{"schedule", `{"$std.cron": "0 */5 * * *"}`, must(jwalk.ParseSchedule("0 */5 * * *")), ""},
+		{"shorthand", `{"$cron": "@daily"}`, must(jwalk.ParseSchedule("@daily")), ""},
+		{"invalid", `{"$std.cron": "0 0 31 2 *"}`, nil, `directive "std.cron": invalid cron expression "0 0 31 2 *"`},
+		{"not a string", `{"$std.cron": ["0", "*"]}`, nil, "cannot unmarshal JSON array into Go string"},
+	})
+}
@@ -0,0 +1,120 @@
+package jwalk
+
+import "strconv"
+
+// ChangeKind classifies a Change.
+type ChangeKind int
+
+const (
+	Added    ChangeKind = iota // present only in the new document
+	Removed                    // present only in the old document
+	Modified                   // present in both with different values
+)
+
+// String returns "added", "removed" or "modified".
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	default:
+		return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Change is a single difference reported by Diff.
+type Change struct {
+	Path string // JSON Pointer (RFC 6901) to the changed value, e.g. "/config/events/2"
+	Kind ChangeKind
+	Old  any // value in the old document; nil when Added
+	New  any // value in the new document; nil when Removed
+}
+
+// Diff returns the changes that turn a into b, recursing into nested
+// Documents and Arrays so that each Change is as deep as possible.
+//
+// Document entries are matched by key (the nth occurrence of a key in a with
+// the nth in b), so reordering keys alone is not a change. Array elements are
+// matched by index: an element inserted at the front shows up as a change to
+// every later index. Leaf values are compared as by Array.IndexOf. Changes are
+// reported depth-first: removals and modifications in a's order, followed by
+// additions in b's order.
+func Diff(a, b Document) []Change {
+	var changes []Change
+	diffDocuments(&changes, "", a, b)
+	return changes
+}
+
+func diffDocuments(changes *[]Change, path string, a, b Document) {
+	type occurrence struct {
+		key string
+		n   int
+	}
+	index := func(d Document) map[occurrence]int {
+		seen := make(map[string]int, len(d))
+		idx := make(map[occurrence]int, len(d))
+		for i, e := range d {
+			idx[occurrence{e.Key, seen[e.Key]}] = i
+			seen[e.Key]++
+		}
+		return idx
+	}
+	ai, bi := index(a), index(b)
+
+	seen := make(map[string]int, len(a))
+	for _, e := range a {
+		occ := occurrence{e.Key, seen[e.Key]}
+		seen[e.Key]++
+		p := path + "/" + escapePointerToken(e.Key)
+		j, ok := bi[occ]
+		if !ok {
+			*changes = append(*changes, Change{Path: p, Kind: Removed, Old: e.Value})
+			continue
+		}
+		diffValues(changes, p, e.Value, b[j].Value)
+	}
+
+	clear(seen)
+	for _, e := range b {
+		occ := occurrence{e.Key, seen[e.Key]}
+		seen[e.Key]++
+		if _, ok := ai[occ]; !ok {
+			*changes = append(*changes, Change{Path: path + "/" + escapePointerToken(e.Key), Kind: Added, New: e.Value})
+		}
+	}
+}
+
+func diffArrays(changes *[]Change, path string, a, b Array) {
+	for i := range max(len(a), len(b)) {
+		p := path + "/" + strconv.Itoa(i)
+		switch {
+		case i >= len(b):
+			*changes = append(*changes, Change{Path: p, Kind: Removed, Old: a[i]})
+		case i >= len(a):
+			*changes = append(*changes, Change{Path: p, Kind: Added, New: b[i]})
+		default:
+			diffValues(changes, p, a[i], b[i])
+		}
+	}
+}
+
+func diffValues(changes *[]Change, path string, a, b any) {
+	switch av := a.(type) {
+	case Document:
+		if bv, ok := b.(Document); ok {
+			diffDocuments(changes, path, av, bv)
+			return
+		}
+	case Array:
+		if bv, ok := b.(Array); ok {
+			diffArrays(changes, path, av, bv)
+			return
+		}
+	}
+	if !valuesEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Kind: Modified, Old: a, New: b})
+	}
+}
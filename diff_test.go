@@ -0,0 +1,75 @@
+package jwalk_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestDiff(t *testing.T) {
+	doc := func(kv ...any) jwalk.Document {
+		d := make(jwalk.Document, 0, len(kv)/2)
+		for i := 0; i < len(kv); i += 2 {
+			d = append(d, jwalk.Entry{Key: kv[i].(string), Value: kv[i+1]})
+		}
+		return d
+	}
+	for _, tt := range []struct {
+		name string
+		a, b jwalk.Document
+		want []jwalk.Change
+	}{
+		{"nil", nil, nil, nil},
+		{"equal", doc("a", 1.0, "b", jwalk.Array{"x"}), doc("a", 1.0, "b", jwalk.Array{"x"}), nil},
+		{"reordered", doc("a", 1.0, "b", 2.0), doc("b", 2.0, "a", 1.0), nil},
+		{"numbers compare by value", doc("n", 1.0, "d", time.Second), doc("n", int64(1), "d", time.Second), nil},
+		{"added, removed and modified", doc("a", 1.0, "b", "x", "c", true), doc("d", nil, "b", "y", "a", 1.0), []jwalk.Change{
+			{Path: "/b", Kind: jwalk.Modified, Old: "x", New: "y"},
+			{Path: "/c", Kind: jwalk.Removed, Old: true},
+			{Path: "/d", Kind: jwalk.Added, New: nil},
+		}},
+		{"nested", doc("cfg", doc("x/y", doc("~", 1.0))), doc("cfg", doc("x/y", doc("~", 2.0))), []jwalk.Change{
+			{Path: "/cfg/x~1y/~0", Kind: jwalk.Modified, Old: 1.0, New: 2.0},
+		}},
+		{"array elements", doc("a", jwalk.Array{1.0, doc("k", 1.0), 3.0}), doc("a", jwalk.Array{1.0, doc("k", 2.0)}), []jwalk.Change{
+			{Path: "/a/1/k", Kind: jwalk.Modified, Old: 1.0, New: 2.0},
+			{Path: "/a/2", Kind: jwalk.Removed, Old: 3.0},
+		}},
+		{"array grown", doc("a", jwalk.Array{}), doc("a", jwalk.Array{"x", "y"}), []jwalk.Change{
+			{Path: "/a/0", Kind: jwalk.Added, New: "x"},
+			{Path: "/a/1", Kind: jwalk.Added, New: "y"},
+		}},
+		{"type changed", doc("v", jwalk.Array{1.0}), doc("v", doc("0", 1.0)), []jwalk.Change{
+			{Path: "/v", Kind: jwalk.Modified, Old: jwalk.Array{1.0}, New: doc("0", 1.0)},
+		}},
+		{"repeated keys", doc("k", 1.0, "k", 2.0), doc("k", 1.0, "k", 3.0, "k", 4.0), []jwalk.Change{
+			{Path: "/k", Kind: jwalk.Modified, Old: 2.0, New: 3.0},
+			{Path: "/k", Kind: jwalk.Added, New: 4.0},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jwalk.Diff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Diff = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeKindString(t *testing.T) {
+	for _, tt := range []struct {
+		kind jwalk.ChangeKind
+		want string
+	}{
+		{jwalk.Added, "added"},
+		{jwalk.Removed, "removed"},
+		{jwalk.Modified, "modified"},
+		{jwalk.ChangeKind(7), "ChangeKind(7)"},
+		{jwalk.ChangeKind(-1), "ChangeKind(-1)"},
+	} {
+		if got := tt.kind.String(); got != tt.want {
+			t.Errorf("ChangeKind(%d).String() = %q, want %q", int(tt.kind), got, tt.want)
+		}
+	}
+}
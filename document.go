@@ -0,0 +1,159 @@
+package jwalk
+
+import (
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Filter returns a new Document containing, in order, only the entries for
+// which fn returns true. d is not modified.
+func (d Document) Filter(fn func(Entry) bool) Document {
+	out := make(Document, 0, len(d))
+	for _, e := range d {
+		if fn(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Project returns a new Document containing only the entries with one of the
+// given keys, in their order in d, e.g. to expose part of a decoded config.
+// Keys not in d are omitted. Values are shared, not copied. d is not modified.
+// ProjectInOrder orders the result by keys instead.
+func (d Document) Project(keys ...string) Document {
+	return d.Filter(func(e Entry) bool { return slices.Contains(keys, e.Key) })
+}
+
+// ProjectInOrder is like Project, but orders the result as keys are given:
+//
+//	doc.ProjectInOrder("name", "id") // {"name": ..., "id": ...}
+//
+// Each key appears at most once, with the value of its first entry in d.
+func (d Document) ProjectInOrder(keys ...string) Document {
+	out := make(Document, 0, len(keys))
+	for i, k := range keys {
+		if slices.Contains(keys[:i], k) {
+			continue
+		}
+		if j := slices.IndexFunc(d, func(e Entry) bool { return e.Key == k }); j >= 0 {
+			out = append(out, d[j])
+		}
+	}
+	return out
+}
+
+// All returns an iterator over the keys and values of d's entries, in order:
+//
+//	for key, value := range doc.All() {
+//	    ...
+//	}
+func (d Document) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for _, e := range d {
+			if !yield(e.Key, e.Value) {
+				return
+			}
+		}
+	}
+}
+
+// ForEach calls fn for each entry of d in order, stopping early if fn returns
+// false, e.g. to search:
+//
+//	var owner any
+//	doc.ForEach(func(key string, value any) bool {
+//	    if strings.EqualFold(key, "owner") {
+//	        owner = value
+//	        return false
+//	    }
+//	    return true
+//	})
+func (d Document) ForEach(fn func(key string, value any) bool) {
+	for _, e := range d {
+		if !fn(e.Key, e.Value) {
+			return
+		}
+	}
+}
+
+// MapValues returns a new Document with the same keys in the same order, and
+// each value replaced by fn(key, value). d is not modified.
+func (d Document) MapValues(fn func(key string, value any) any) Document {
+	out := make(Document, len(d))
+	for i, e := range d {
+		out[i] = Entry{Key: e.Key, Value: fn(e.Key, e.Value)}
+	}
+	return out
+}
+
+// Rename changes the key of the first entry with key oldKey to newKey, keeping
+// the entry's position and value, and reports whether it did. It reports
+// false, leaving d unchanged, if no entry has key oldKey or if another entry
+// already has key newKey, so a rename never introduces a duplicate key; to
+// allow one, assign the entry's Key field directly.
+func (d *Document) Rename(oldKey, newKey string) bool {
+	i := slices.IndexFunc(*d, func(e Entry) bool { return e.Key == oldKey })
+	if i < 0 {
+		return false
+	}
+	if oldKey == newKey {
+		return true
+	}
+	if slices.ContainsFunc(*d, func(e Entry) bool { return e.Key == newKey }) {
+		return false
+	}
+	(*d)[i].Key = newKey
+	return true
+}
+
+// WalkMutate walks every value in d depth-first, in order, calling fn with the
+// value's JSON Pointer (RFC 6901) path, e.g. "/config/events/0". If fn returns
+// (newValue, true) the value is replaced in place and the replacement is not
+// walked further; otherwise nested Document and Array values are walked.
+//
+// Nested containers are updated in place, so the changes are visible through
+// every reference to them.
+func (d *Document) WalkMutate(fn func(path string, value any) (any, bool)) {
+	walkDocument(*d, "", fn)
+}
+
+func walkDocument(d Document, path string, fn func(string, any) (any, bool)) {
+	for i := range d {
+		p := path + "/" + escapePointerToken(d[i].Key)
+		if nv, ok := fn(p, d[i].Value); ok {
+			d[i].Value = nv
+			continue
+		}
+		walkChildren(d[i].Value, p, fn)
+	}
+}
+
+func walkArray(a Array, path string, fn func(string, any) (any, bool)) {
+	for i := range a {
+		p := path + "/" + strconv.Itoa(i)
+		if nv, ok := fn(p, a[i]); ok {
+			a[i] = nv
+			continue
+		}
+		walkChildren(a[i], p, fn)
+	}
+}
+
+func walkChildren(v any, path string, fn func(string, any) (any, bool)) {
+	switch val := v.(type) {
+	case Document:
+		walkDocument(val, path, fn)
+	case Array:
+		walkArray(val, path, fn)
+	}
+}
+
+// pointerEscaper encodes "~" and "/" in a JSON Pointer token.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapePointerToken(s string) string {
+	return pointerEscaper.Replace(s)
+}
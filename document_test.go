@@ -0,0 +1,81 @@
+package jwalk_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+
+	"github.com/calumari/jwalk"
+)
+
+type mixed struct {
+	Name  string           `json:"name"`
+	Count int              `json:"count"`
+	Meta  jwalk.Document   `json:"meta"`
+	Raw   jwalk.Document   `json:"raw"`
+	Ptr   *jwalk.Document  `json:"ptr"`
+	Items []jwalk.Document `json:"items"`
+	Extra any              `json:"extra"`
+	Plain map[string]int   `json:"plain"`
+}
+
+func TestUnmarshalMixedStruct(t *testing.T) {
+	reg, err := jwalk.NewRegistry(jwalk.WithStdlib())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := []byte(`{
+		"name": "svc",
+		"count": 3,
+		"meta": {"z": 1, "a": {"y": 2, "b": 3}, "m": [{"d": 1, "c": 2}], "t": {"$std.duration": "1s"}},
+		"raw": {"$std.duration": "1s"},
+		"ptr": {"q": 1, "p": 2},
+		"items": [{"k2": 1, "k1": 2}, {}],
+		"extra": {"wait": {"$std.duration": "2s"}, "b": 1, "a": 2},
+		"plain": {"x": 1}
+	}`)
+	for _, decode := range []struct {
+		name string
+		fn   func(*mixed) error
+	}{
+		{"Registry.Unmarshal", func(m *mixed) error { return reg.Unmarshal(in, m) }},
+		{"json.Unmarshal", func(m *mixed) error { return json.Unmarshal(in, m, reg.Options()...) }},
+	} {
+		t.Run(decode.name, func(t *testing.T) {
+			var m mixed
+			if err := decode.fn(&m); err != nil {
+				t.Fatal(err)
+			}
+			if m.Name != "svc" || m.Count != 3 || m.Plain["x"] != 1 {
+				t.Errorf("typed fields = %q, %d, %v", m.Name, m.Count, m.Plain)
+			}
+
+			// a Document field keeps its order throughout; it is not itself
+			// a directive, but the values within it may be
+			if t1 := m.Meta[len(m.Meta)-1].Value; t1 != time.Second {
+				t.Errorf("meta.t = %v, want 1s", t1)
+			}
+			const meta = `{"z":1,"a":{"y":2,"b":3},"m":[{"d":1,"c":2}]}`
+			if got, err := reg.Marshal(m.Meta[:len(m.Meta)-1]); err != nil || string(got) != meta {
+				t.Errorf("meta = %s (%v), want %s", got, err, meta)
+			}
+			if got, err := reg.Marshal(m.Raw); err != nil || string(got) != `{"$std.duration":"1s"}` {
+				t.Errorf("raw = %s (%v), want the sentinel as data", got, err)
+			}
+			if got, err := reg.Marshal(m.Ptr); err != nil || string(got) != `{"q":1,"p":2}` {
+				t.Errorf("ptr = %s (%v)", got, err)
+			}
+			if got, err := reg.Marshal(m.Items); err != nil || string(got) != `[{"k2":1,"k1":2},{}]` {
+				t.Errorf("items = %s (%v)", got, err)
+			}
+
+			// an any field is decoded by jwalk, directives included
+			extra, ok := m.Extra.(jwalk.Document)
+			if !ok || len(extra) != 3 || extra[0].Value != 2*time.Second || extra[1].Key != "b" || extra[2].Key != "a" {
+				t.Errorf("extra = %#v, want ordered Document with a dispatched directive", m.Extra)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package jwalk
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// NewEnumDirective constructs a Directive that decodes a string into the
+// constant it maps to, e.g. with
+//
+//	type Status int
+//
+//	const (
+//	    Active Status = iota
+//	    Suspended
+//	)
+//
+//	d := jwalk.NewEnumDirective("status", map[string]Status{
+//	    "active":    Active,
+//	    "suspended": Suspended,
+//	})
+//
+// the value {"$status": "active"} decodes to Active. A string with no mapping
+// produces an error listing the allowed values. mapping is copied, so later
+// changes to it do not affect the Directive.
+func NewEnumDirective[T any](name string, mapping map[string]T) *Directive {
+	mapping = maps.Clone(mapping)
+	return NewDirective(name, func(dec *jsontext.Decoder) (T, error) {
+		return unmarshalEnum(dec, mapping)
+	})
+}
+
+func unmarshalEnum[T any](dec *jsontext.Decoder, mapping map[string]T) (T, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		var zero T
+		return zero, err
+	}
+	v, ok := mapping[s]
+	if !ok {
+		known := slices.Sorted(maps.Keys(mapping))
+		return v, fmt.Errorf("unknown value %q (expected one of %s)", s, strings.Join(known, ", "))
+	}
+	return v, nil
+}
@@ -0,0 +1,64 @@
+package jwalk
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// EnvDirective is a Directive registered as "env" that decodes values of
+// either form:
+//
+//	{"$env": "HOME"}                                  // required variable
+//	{"$env": {"name":"PORT","default":"8080"}}        // with fallback
+//
+// into the string value of the named environment variable. It is not part of
+// the standard set and must be registered explicitly.
+var EnvDirective = NewEnvDirective("env")
+
+// NewEnvDirective constructs an environment-variable Directive registered
+// under the given name. See EnvDirective for the accepted forms.
+//
+// A variable that is unset and has no default produces an error. A variable
+// that is set to the empty string is returned as-is.
+func NewEnvDirective(name string) *Directive {
+	return NewDirective(name, unmarshalEnv)
+}
+
+func unmarshalEnv(dec *jsontext.Decoder) (string, error) {
+	// Support object with name/default or plain string.
+	if dec.PeekKind() == '{' {
+		var aux struct {
+			Name    string  `json:"name"`
+			Default *string `json:"default"`
+		}
+		if err := json.UnmarshalDecode(dec, &aux); err != nil {
+			return "", err
+		}
+		if aux.Name == "" {
+			return "", fmt.Errorf("environment variable name is empty")
+		}
+		if v, ok := os.LookupEnv(aux.Name); ok {
+			return v, nil
+		}
+		if aux.Default != nil {
+			return *aux.Default, nil
+		}
+		return "", fmt.Errorf("environment variable %q not set", aux.Name)
+	}
+
+	var name string
+	if err := json.UnmarshalDecode(dec, &name); err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("environment variable name is empty")
+	}
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q not set", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,48 @@
+package jwalk_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestEnvDirective(t *testing.T) {
+	t.Setenv("JWALK_TEST_HOST", "db.internal")
+	t.Setenv("JWALK_TEST_EMPTY", "")
+	reg, err := jwalk.NewRegistry(jwalk.EnvDirective)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"string", `{"$env": "JWALK_TEST_HOST"}`, "db.internal", ""},
+		{"set to empty", `{"$env": "JWALK_TEST_EMPTY"}`, "", ""},
+		{"object", `{"$env": {"name": "JWALK_TEST_HOST"}}`, "db.internal", ""},
+		{"object/set overrides default", `{"$env": {"name": "JWALK_TEST_HOST", "default": "localhost"}}`, "db.internal", ""},
+		{"object/default", `{"$env": {"name": "JWALK_TEST_UNSET", "default": "localhost"}}`, "localhost", ""},
+		{"object/empty default", `{"$env": {"name": "JWALK_TEST_UNSET", "default": ""}}`, "", ""},
+		{"unset", `{"$env": "JWALK_TEST_UNSET"}`, "", `environment variable "JWALK_TEST_UNSET" not set`},
+		{"object/unset", `{"$env": {"name": "JWALK_TEST_UNSET"}}`, "", `environment variable "JWALK_TEST_UNSET" not set`},
+		{"empty name", `{"$env": ""}`, "", "environment variable name is empty"},
+		{"object/empty name", `{"$env": {"default": "x"}}`, "", "environment variable name is empty"},
+		{"not a string", `{"$env": 1}`, "", "cannot unmarshal JSON number into Go string"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reg.DecodeValue([]byte(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecodeValue = %v, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("DecodeValue = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -14,7 +14,7 @@ import (
 
 func main() {
 	// Build a registry that knows how to handle time and duration sentinels.
-	reg, err := jwalk.NewRegistry(jwalk.WithDirective(jwalk.StdTimeDirective), jwalk.WithDirective(jwalk.StdDurationDirective))
+	reg, err := jwalk.NewRegistry(jwalk.StdTimeDirective, jwalk.StdDurationDirective)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,163 @@
+package jwalk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// indentUnit is the per-level indentation used by String.
+const indentUnit = "  "
+
+// String returns an indented, ordered, JSON-like rendering of the document
+// intended for humans (logs, test failures, golden files). It is not a
+// canonical encoding; use Registry.Marshal for that.
+//
+// Directive results are rendered readably, e.g. time.Time as RFC3339 and
+// time.Duration in its String form.
+func (d Document) String() string {
+	var b strings.Builder
+	writePretty(&b, d, 0)
+	return b.String()
+}
+
+// String returns an indented, ordered, JSON-like rendering of the array. See
+// Document.String.
+func (a Array) String() string {
+	var b strings.Builder
+	writePretty(&b, a, 0)
+	return b.String()
+}
+
+// writePretty appends the pretty rendering of v at the given depth.
+func writePretty(b *strings.Builder, v any, depth int) {
+	switch val := v.(type) {
+	case Document:
+		if len(val) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i, e := range val {
+			writeIndent(b, depth+1)
+			b.WriteString(strconv.Quote(e.Key))
+			b.WriteString(": ")
+			writePretty(b, e.Value, depth+1)
+			if i < len(val)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		writeIndent(b, depth)
+		b.WriteByte('}')
+
+	case Array:
+		if len(val) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, elem := range val {
+			writeIndent(b, depth+1)
+			writePretty(b, elem, depth+1)
+			if i < len(val)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		writeIndent(b, depth)
+		b.WriteByte(']')
+
+	case nil:
+		b.WriteString("null")
+	case string:
+		b.WriteString(strconv.Quote(val))
+	case time.Time:
+		b.WriteString(val.Format(time.RFC3339Nano))
+	case fmt.Stringer:
+		b.WriteString(val.String())
+	default:
+		fmt.Fprintf(b, "%v", val)
+	}
+}
+
+func writeIndent(b *strings.Builder, depth int) {
+	for range depth {
+		b.WriteString(indentUnit)
+	}
+}
+
+// GoString returns a compact, single-line debug rendering of the document for
+// use with the %#v verb, e.g.
+//
+//	jwalk.Document{"name": "example", "tags": jwalk.Array{"a", "b"}}
+func (d Document) GoString() string {
+	var b strings.Builder
+	writeGo(&b, d)
+	return b.String()
+}
+
+// GoString returns a compact, single-line debug rendering of the array for use
+// with the %#v verb. See Document.GoString.
+func (a Array) GoString() string {
+	var b strings.Builder
+	writeGo(&b, a)
+	return b.String()
+}
+
+// GoString returns a compact, single-line debug rendering of the entry for use
+// with the %#v verb, e.g.
+//
+//	jwalk.Entry{"name": "example"}
+func (e Entry) GoString() string {
+	var b strings.Builder
+	b.WriteString("jwalk.Entry{")
+	writeGoEntry(&b, e)
+	b.WriteByte('}')
+	return b.String()
+}
+
+// writeGo appends the compact debug rendering of v.
+func writeGo(b *strings.Builder, v any) {
+	switch val := v.(type) {
+	case Document:
+		if val == nil {
+			b.WriteString("jwalk.Document(nil)")
+			return
+		}
+		b.WriteString("jwalk.Document{")
+		for i, e := range val {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeGoEntry(b, e)
+		}
+		b.WriteByte('}')
+
+	case Array:
+		if val == nil {
+			b.WriteString("jwalk.Array(nil)")
+			return
+		}
+		b.WriteString("jwalk.Array{")
+		for i, elem := range val {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeGo(b, elem)
+		}
+		b.WriteByte('}')
+
+	case nil:
+		b.WriteString("nil")
+	default:
+		fmt.Fprintf(b, "%#v", val)
+	}
+}
+
+func writeGoEntry(b *strings.Builder, e Entry) {
+	b.WriteString(strconv.Quote(e.Key))
+	b.WriteString(": ")
+	writeGo(b, e.Value)
+}
@@ -0,0 +1,99 @@
+package jwalk_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+// FuzzUnmarshal feeds arbitrary input through the object, array and directive
+// decoding. Decoding must not panic, and a value that decodes successfully as
+// an array element must leave the decoder at the next element. Without
+// directives, decoding must also round trip through Marshal.
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range []string{
+		`{}`,
+		`[]`,
+		`null`,
+		`{"a": 1, "b": [true, null, "x"], "c": {"d": -1.5e3}}`,
+		`[[[[]]], {"": {}}]`,
+		`{"$std.time": "2023-10-05T14:00:00Z"}`,
+		`{"$std.duration": "1h"}`,
+		`{"a": {"$std.json": "[1, {\"b\": 2}]"}}`,
+		`{"$std.time": "not a time"}`,
+		`{"$std.time": "2023-10-05T14:00:00Z", "extra": 1}`,
+		`{"x": 1, "$std.duration": "1s"}`,
+		`{"$unknown": 1}`,
+		`{"$": 1}`,
+		`{"$std.json": {"$std.json": "1"}}`,
+		`[{"$std.duration": 5}, 1]`,
+		`{"a": 1, "a": 2}`,
+		`{"a": [1, 2`,
+		`{"a" 1}`,
+		`"\ud800"`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	plain, err := jwalk.NewRegistry()
+	if err != nil {
+		f.Fatal(err)
+	}
+	regs := []*jwalk.Registry{plain}
+	for _, opts := range [][]jwalk.Option{
+		{jwalk.WithStdlib()},
+		{jwalk.WithStdlib(), jwalk.WithSentinelScan(), jwalk.WithStringInterning(), jwalk.WithMaxTotalValues(1000)},
+		{jwalk.WithStdlib(), jwalk.WithNestedSentinelsAsData(), jwalk.WithScratch()},
+	} {
+		reg, err := jwalk.NewRegistry(opts...)
+		if err != nil {
+			f.Fatal(err)
+		}
+		regs = append(regs, reg)
+	}
+
+	f.Fuzz(func(t *testing.T, in []byte) {
+		for _, reg := range regs {
+			var v any
+			_ = reg.Unmarshal(in, &v)
+			var doc jwalk.Document
+			_ = reg.Unmarshal(in, &doc)
+
+			if !jsontext.Value(in).IsValid() {
+				continue
+			}
+			wrapped := append(append([]byte("["), in...), `, "next"]`...)
+			dec := jsontext.NewDecoder(bytes.NewReader(wrapped), reg.Options()...)
+			if _, err := dec.ReadToken(); err != nil {
+				t.Fatal(err)
+			}
+			var elem any
+			if err := json.UnmarshalDecode(dec, &elem); err != nil {
+				continue
+			}
+			if tok, err := dec.ReadToken(); err != nil || tok.String() != "next" {
+				t.Fatalf("after decoding %s: next token = %v, %v; want \"next\"", in, tok, err)
+			}
+		}
+
+		var v any
+		if err := plain.Unmarshal(in, &v); err != nil {
+			return
+		}
+		out, err := plain.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal(%#v): %v", v, err)
+		}
+		var v2 any
+		if err := plain.Unmarshal(out, &v2); err != nil {
+			t.Fatalf("Unmarshal(Marshal(%s)) = %s: %v", in, out, err)
+		}
+		if out2, err := plain.Marshal(v2); err != nil || !bytes.Equal(out, out2) {
+			t.Fatalf("round trip of %s: %s, then %s (%v)", in, out, out2, err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package jwalk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// DefaultMaxIncludeDepth is the include nesting limit used by
+// NewIncludeDirective when a non-positive maxDepth is given.
+const DefaultMaxIncludeDepth = 8
+
+// NewIncludeDirective constructs a Directive registered under the given name
+// that decodes values of the form:
+//
+//	{"$include": "partials/db.json"}
+//
+// by reading the named file and decoding it with the same options (and
+// therefore the same Registry) as the enclosing document, substituting the
+// result. Included files may themselves contain include directives.
+//
+// Paths are always resolved relative to baseDir, and may not escape it (via
+// ".." or symlinks). Nesting deeper than maxDepth, or an include cycle, is
+// reported as an error.
+func NewIncludeDirective(name, baseDir string, maxDepth int) *Directive {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxIncludeDepth
+	}
+	inc := &includer{
+		baseDir:  baseDir,
+		maxDepth: maxDepth,
+	}
+	d := NewRawDirective(name, inc.unmarshal)
+	d.stateful = true
+	return d
+}
+
+// includer reads included files. The chain of files being included is kept
+// in the state of the decode (see decodeState.includes), which the decoders
+// of included files share, so it survives decoding a value again on another
+// decoder, e.g. under WithSentinelScan.
+type includer struct {
+	baseDir  string
+	maxDepth int
+}
+
+func (inc *includer) unmarshal(dec *jsontext.Decoder) (any, error) {
+	var path string
+	if err := json.UnmarshalDecode(dec, &path); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.New("include path is empty")
+	}
+	name := filepath.Clean(path)
+
+	st := stateOf(dec)
+	var chain []string
+	if st != nil {
+		chain = st.includes[inc]
+	}
+
+	if slices.Contains(chain, name) {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(slices.Clone(chain), name), " -> "))
+	}
+	if len(chain) >= inc.maxDepth {
+		return nil, fmt.Errorf("include %q exceeds max depth %d", path, inc.maxDepth)
+	}
+
+	f, err := os.OpenInRoot(inc.baseDir, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Decode with the enclosing decoder's options so the same unmarshalers
+	// (and registry) apply to the included file.
+	nested, release := newSubDecoder(dec, f)
+	defer release()
+
+	if st != nil {
+		if st.includes == nil {
+			st.includes = make(map[*includer][]string)
+		}
+		st.includes[inc] = append(slices.Clip(chain), name)
+		defer func() { st.includes[inc] = chain }()
+	}
+
+	var v any
+	if err := json.UnmarshalDecode(nested, &v); err != nil {
+		return nil, fmt.Errorf("include %q: %w", path, flattenPath(err))
+	}
+	if _, err := nested.ReadToken(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+		return nil, fmt.Errorf("include %q: %w", path, err)
+	}
+	return v, nil
+}
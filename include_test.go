@@ -0,0 +1,244 @@
+package jwalk_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+// writeFiles writes the named files into a new temporary directory and
+// returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// within runs fn, failing the test if it has not returned after a few
+// seconds, e.g. because an include cycle went undetected.
+func within(t *testing.T, fn func() error) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("decode did not finish")
+		return nil
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"self.json": `{"x": {"$include": "self.json"}}`,
+		"a.json":    `{"b": {"$include": "b.json"}}`,
+		"b.json":    `[{"$include": "a.json"}]`,
+	})
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"self", `{"$include": "self.json"}`, "include cycle: self.json -> self.json"},
+		{"mutual", `{"$include": "a.json"}`, "include cycle: a.json -> b.json -> a.json"},
+		{"nested", `{"top": [{"$include": "a.json"}]}`, "include cycle: a.json -> b.json -> a.json"},
+	}
+	for _, opts := range []struct {
+		name string
+		opts []jwalk.Option
+	}{
+		{"default", nil},
+		{"SentinelScan", []jwalk.Option{jwalk.WithSentinelScan()}},
+		{"Scratch", []jwalk.Option{jwalk.WithScratch()}},
+	} {
+		reg, err := jwalk.NewRegistry(append(opts.opts, jwalk.NewIncludeDirective("include", dir, 0))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			t.Run(opts.name+"/"+tt.name, func(t *testing.T) {
+				var v any
+				err := within(t, func() error { return reg.Unmarshal([]byte(`{"root": `+tt.in+`}`), &v) })
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("Unmarshal error = %v, want %q", err, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// objectInclude is an ObjectMode directive that decodes its sentinel's
+// "value" member into any, dispatching any directive within it.
+var objectInclude = jwalk.NewObjectDirective("wrap", func(dec *jsontext.Decoder) (any, error) {
+	var obj struct {
+		Value any `json:"value"`
+	}
+	err := json.UnmarshalDecode(dec, &obj)
+	return obj.Value, err
+})
+
+func TestIncludeCycleThroughObjectMode(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"loop.json": `{"$wrap": true, "value": {"$include": "loop.json"}}`,
+	})
+	reg, err := jwalk.NewRegistry(objectInclude, jwalk.NewIncludeDirective("include", dir, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v any
+	err = within(t, func() error { return reg.Unmarshal([]byte(`{"$include": "loop.json"}`), &v) })
+	if err == nil || !strings.Contains(err.Error(), "include cycle: loop.json -> loop.json") {
+		t.Fatalf("Unmarshal error = %v, want include cycle", err)
+	}
+}
+
+func TestIncludeJSONUnmarshal(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.json": `{"b": {"$include": "b.json"}}`,
+		"b.json": `{"a": {"$include": "a.json"}}`,
+	})
+	reg, err := jwalk.NewRegistry(jwalk.NewIncludeDirective("include", dir, 0), jwalk.WithSentinelScan())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v any
+	err = within(t, func() error { return json.Unmarshal([]byte(`{"$include": "a.json"}`), &v, reg.Options()...) })
+	if err == nil || !strings.Contains(err.Error(), "include cycle: a.json -> b.json -> a.json") {
+		t.Fatalf("json.Unmarshal error = %v, want include cycle", err)
+	}
+}
+
+func TestIncludeSiblings(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"leaf.json": `[1, 2]`,
+		"mid.json":  `{"one": {"$include": "leaf.json"}, "two": {"$include": "leaf.json"}}`,
+	})
+	reg, err := jwalk.NewRegistry(jwalk.NewIncludeDirective("include", dir, 0), jwalk.WithRequireObjectRoot())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same file included twice, but not within itself, is no cycle, and
+	// an included array is not the root of the decode
+	var v any
+	if err := reg.Unmarshal([]byte(`{"a": {"$include": "mid.json"}, "b": {"$include": "mid.json"}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	got, err := reg.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":{"one":[1,2],"two":[1,2]},"b":{"one":[1,2],"two":[1,2]}}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestIncludeMaxDepth(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"1.json": `{"$include": "2.json"}`,
+		"2.json": `{"$include": "3.json"}`,
+		"3.json": `{"done": true}`,
+	})
+	for _, tt := range []struct {
+		depth   int
+		wantErr bool
+	}{{2, true}, {3, false}} {
+		reg, err := jwalk.NewRegistry(jwalk.NewIncludeDirective("include", dir, tt.depth), jwalk.WithSentinelScan())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v any
+		err = reg.Unmarshal([]byte(`{"$include": "1.json"}`), &v)
+		if gotErr := err != nil && strings.Contains(err.Error(), "exceeds max depth"); gotErr != tt.wantErr {
+			t.Errorf("max depth %d: error = %v, want depth error %v", tt.depth, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIncludeInvokeDirective(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"self.json": `{"x": {"$include": "self.json"}}`,
+		"1.json":    `{"$include": "2.json"}`,
+		"2.json":    `{"$include": "3.json"}`,
+		"3.json":    `{"done": true}`,
+	})
+	for _, tt := range []struct {
+		name     string
+		maxDepth int
+		in       string
+		want     string
+	}{
+		{"self", 0, `"self.json"`, `directive "include": include "self.json": x: directive "include": include cycle: self.json -> self.json`},
+		{"max depth", 2, `"1.json"`, `directive "include": include "1.json": (root): directive "include": include "2.json": (root): directive "include": include "3.json" exceeds max depth 2`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(jwalk.NewIncludeDirective("include", dir, tt.maxDepth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a decoder of the caller's, with no decode of the Registry's
+			// around it
+			dec := jsontext.NewDecoder(strings.NewReader(tt.in), reg.Options()...)
+			err = within(t, func() error {
+				_, err := reg.InvokeDirective("include", dec)
+				return err
+			})
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("InvokeDirective error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeErrorPath(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.json": `{"t": {"$std.time": "bad"}}`,
+	})
+	for _, opts := range []struct {
+		name string
+		opts []jwalk.Option
+	}{
+		{"default", nil},
+		{"SentinelScan", []jwalk.Option{jwalk.WithSentinelScan()}},
+	} {
+		t.Run(opts.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(append(opts.opts, jwalk.WithStdlib(), jwalk.NewIncludeDirective("include", dir, 0))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v any
+			err = reg.Unmarshal([]byte(`{"cfg": {"x": [1, {"$include": "a.json"}]}}`), &v)
+
+			// the error is located at the include, in the input, and names
+			// the file and the path within it
+			var pe *jwalk.PathError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Unmarshal error = %v, want a PathError", err)
+			}
+			if pe.Pointer != "/cfg/x/1" {
+				t.Errorf("Pointer = %q, want /cfg/x/1", pe.Pointer)
+			}
+			const want = `cfg.x[1]: directive "include": include "a.json": t: directive "std.time": parsing time "bad"`
+			if !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("Unmarshal error = %v, want prefix %q", err, want)
+			}
+		})
+	}
+}
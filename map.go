@@ -0,0 +1,241 @@
+package jwalk
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// StdMapDirective constructs a Directive that decodes values of either form:
+//
+//	{"$std.map": [[1, "a"], [2, "b"]]}                     // key type inferred
+//	{"$std.map": {"key": "int", "pairs": [[1, "a"]]}}     // key type explicit
+//
+// into a Go map, carrying non-string keys through JSON as key/value pairs.
+// Values are decoded like any other value, so they may be Documents, Arrays or
+// directive results.
+//
+// Supported key types are "string", "int", "float" and "bool", producing
+// map[string]any, map[int]any, map[float64]any and map[bool]any. When the key
+// type is omitted it is inferred from the keys: integers give map[int]any,
+// other numbers map[float64]any, and so on; keys of mixed kinds give
+// map[any]any, as does an empty pairs array. Keys that do not fit the key
+// type, and duplicate keys, produce an error.
+var StdMapDirective = NewRawDirective("std.map", unmarshalMap)
+
+// StdOrderedDirective constructs a Directive that decodes values of the form:
+//
+//	{"$std.ordered": [["b", 2], ["a", 1]]}
+//
+// into a Document with one entry per pair, in array order, for producers that
+// cannot guarantee object key order but can emit pairs. Values are decoded
+// like any other value. A pair that is not a two-element array, or whose key
+// is not a string, produces an error. Repeated keys are kept, as in any
+// Document.
+var StdOrderedDirective = NewDirective("std.ordered", unmarshalOrdered)
+
+// mapPair is a decoded key/value pair with the key still in JSON form.
+type mapPair struct {
+	key jsontext.Value
+	val any
+}
+
+func unmarshalMap(dec *jsontext.Decoder) (any, error) {
+	// Support object with key/pairs or plain pairs array.
+	if dec.PeekKind() != '{' {
+		pairs, err := readMapPairs(dec)
+		if err != nil {
+			return nil, err
+		}
+		return buildMap(inferMapKey(pairs), pairs)
+	}
+
+	if _, err := dec.ReadToken(); err != nil { // '{'
+		return nil, err
+	}
+	var keyType string
+	var pairs []mapPair
+	for dec.PeekKind() != '}' {
+		var name string
+		if err := json.UnmarshalDecode(dec, &name); err != nil {
+			return nil, err
+		}
+		var err error
+		switch name {
+		case "key":
+			err = json.UnmarshalDecode(dec, &keyType)
+		case "pairs":
+			pairs, err = readMapPairs(dec)
+		default:
+			err = dec.SkipValue()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+	if _, err := dec.ReadToken(); err != nil { // '}'
+		return nil, err
+	}
+
+	if keyType == "" {
+		keyType = inferMapKey(pairs)
+	}
+	return buildMap(keyType, pairs)
+}
+
+func unmarshalOrdered(dec *jsontext.Decoder) (Document, error) {
+	pairs, err := readMapPairs(dec)
+	if err != nil {
+		return nil, err
+	}
+	d := make(Document, len(pairs))
+	for i, p := range pairs {
+		if p.key.Kind() != '"' {
+			return nil, fmt.Errorf("pair %d key %s is not a string", i, p.key)
+		}
+		if err := json.Unmarshal(p.key, &d[i].Key); err != nil {
+			return nil, fmt.Errorf("pair %d key: %w", i, err)
+		}
+		d[i].Value = p.val
+	}
+	return d, nil
+}
+
+// readMapPairs reads an array of two-element [key, value] arrays.
+func readMapPairs(dec *jsontext.Decoder) ([]mapPair, error) {
+	if k := dec.PeekKind(); k != '[' {
+		return nil, fmt.Errorf("expected array of pairs, got %s", kindName(k))
+	}
+	if _, err := dec.ReadToken(); err != nil { // '['
+		return nil, err
+	}
+
+	var pairs []mapPair
+	for i := 0; dec.PeekKind() != ']'; i++ {
+		if k := dec.PeekKind(); k != '[' {
+			return nil, fmt.Errorf("pair %d is %s, not a [key, value] array", i, kindName(k))
+		}
+		if _, err := dec.ReadToken(); err != nil { // '['
+			return nil, err
+		}
+		if dec.PeekKind() == ']' {
+			return nil, fmt.Errorf("pair %d is missing its key", i)
+		}
+		key, err := dec.ReadValue()
+		if err != nil {
+			return nil, fmt.Errorf("pair %d key: %w", i, err)
+		}
+		key = key.Clone()
+		if dec.PeekKind() == ']' {
+			return nil, fmt.Errorf("pair %d is missing its value", i)
+		}
+		var val any
+		if err := json.UnmarshalDecode(dec, &val); err != nil {
+			return nil, fmt.Errorf("pair %d value: %w", i, err)
+		}
+		if dec.PeekKind() != ']' {
+			return nil, fmt.Errorf("pair %d has more than two elements", i)
+		}
+		if _, err := dec.ReadToken(); err != nil { // ']'
+			return nil, err
+		}
+		pairs = append(pairs, mapPair{key: key, val: val})
+	}
+
+	if _, err := dec.ReadToken(); err != nil { // ']'
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// inferMapKey returns the narrowest key type that fits every key in pairs, or
+// "" if the keys are of mixed kinds or there are none.
+func inferMapKey(pairs []mapPair) string {
+	var keyType string
+	for i, p := range pairs {
+		var t string
+		switch p.key.Kind() {
+		case '"':
+			t = "string"
+		case 't', 'f':
+			t = "bool"
+		case '0':
+			t = "float"
+			if _, err := strconv.Atoi(string(p.key)); err == nil {
+				t = "int"
+			}
+		default:
+			return ""
+		}
+		switch {
+		case i == 0, t == keyType:
+			keyType = t
+		case t == "float" && keyType == "int", t == "int" && keyType == "float":
+			keyType = "float"
+		default:
+			return ""
+		}
+	}
+	return keyType
+}
+
+// buildMap converts pairs into a map keyed by keyType. An empty keyType builds
+// a map[any]any.
+func buildMap(keyType string, pairs []mapPair) (any, error) {
+	switch keyType {
+	case "string":
+		return fillMap(pairs, func(k jsontext.Value) (string, error) {
+			var s string
+			err := json.Unmarshal(k, &s)
+			return s, err
+		})
+	case "int":
+		return fillMap(pairs, func(k jsontext.Value) (int, error) {
+			var n int
+			err := json.Unmarshal(k, &n)
+			return n, err
+		})
+	case "float":
+		return fillMap(pairs, func(k jsontext.Value) (float64, error) {
+			var f float64
+			err := json.Unmarshal(k, &f)
+			return f, err
+		})
+	case "bool":
+		return fillMap(pairs, func(k jsontext.Value) (bool, error) {
+			var b bool
+			err := json.Unmarshal(k, &b)
+			return b, err
+		})
+	case "":
+		return fillMap(pairs, func(k jsontext.Value) (any, error) {
+			switch k.Kind() {
+			case '"', '0', 't', 'f':
+			default:
+				return nil, fmt.Errorf("must be a string, number or boolean")
+			}
+			var v any
+			err := json.Unmarshal(k, &v)
+			return v, err
+		})
+	default:
+		return nil, fmt.Errorf("unsupported key type %q (expected string, int, float or bool)", keyType)
+	}
+}
+
+func fillMap[K comparable](pairs []mapPair, parseKey func(jsontext.Value) (K, error)) (map[K]any, error) {
+	m := make(map[K]any, len(pairs))
+	for i, p := range pairs {
+		k, err := parseKey(p.key)
+		if err != nil {
+			return nil, fmt.Errorf("pair %d key %s: %w", i, p.key, err)
+		}
+		if _, dup := m[k]; dup {
+			return nil, fmt.Errorf("pair %d: duplicate key %s", i, p.key)
+		}
+		m[k] = p.val
+	}
+	return m, nil
+}
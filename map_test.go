@@ -0,0 +1,35 @@
+package jwalk_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestStdMapDirective(t *testing.T) {
+	testStdlib(t, []stdlibTest{
+		{"int keys", `{"$std.map": [[1, "a"], [2, "b"]]}`, map[int]any{1: "a", 2: "b"}, ""},
+		{"float keys", `{"$std.map": [[1, "a"], [2.5, "b"]]}`, map[float64]any{1: "a", 2.5: "b"}, ""},
+		{"string keys", `{"$std.map": [["x", 1]]}`, map[string]any{"x": 1.0}, ""},
+		{"bool keys", `{"$std.map": [[true, "on"], [false, "off"]]}`, map[bool]any{true: "on", false: "off"}, ""},
+		{"mixed keys", `{"$std.map": [[1, "a"], ["1", "b"]]}`, map[any]any{1.0: "a", "1": "b"}, ""},
+		{"empty", `{"$std.map": []}`, map[any]any{}, ""},
+		{"values decoded", `{"$std.map": [[1, {"$std.duration": "1s"}], [2, {"b": [1]}]]}`,
+			map[int]any{1: time.Second, 2: jwalk.Document{{Key: "b", Value: jwalk.Array{1.0}}}}, ""},
+		{"explicit key", `{"$std.map": {"key": "float", "pairs": [[1, "a"]]}}`, map[float64]any{1: "a"}, ""},
+		{"explicit key/inferred", `{"$std.map": {"pairs": [[1, "a"]], "other": 0}}`, map[int]any{1: "a"}, ""},
+		{"object/no pairs", `{"$std.map": {"1": "a"}}`, map[any]any{}, ""},
+		{"object/not pairs", `{"$std.map": {"key": "int", "pairs": 1}}`, nil, `field "pairs": expected array of pairs, got number`},
+		{"not an array", `{"$std.map": "a"}`, nil, "expected array of pairs, got string"},
+		{"pair not an array", `{"$std.map": [1]}`, nil, "pair 0 is number, not a [key, value] array"},
+		{"missing key", `{"$std.map": [[]]}`, nil, "pair 0 is missing its key"},
+		{"missing value", `{"$std.map": [[1, "a"], [2]]}`, nil, "pair 1 is missing its value"},
+		{"extra element", `{"$std.map": [[1, "a", "b"]]}`, nil, "pair 0 has more than two elements"},
+		{"duplicate key", `{"$std.map": [[1, "a"], [1.0, "b"]]}`, nil, "pair 1: duplicate key 1.0"},
+		{"key type mismatch", `{"$std.map": {"key": "int", "pairs": [[1.5, "a"]]}}`, nil, "pair 0 key 1.5"},
+		{"unsupported key type", `{"$std.map": {"key": "uint", "pairs": []}}`, nil, `unsupported key type "uint"`},
+		{"object key", `{"$std.map": [[{}, "a"], [1, "b"]]}`, nil, "pair 0 key {}: must be a string, number or boolean"},
+		{"bad value", `{"$std.map": [[1, {"$std.duration": "x"}]]}`, nil, `$std.map[0][1]: directive "std.duration": time: invalid duration "x"`},
+	})
+}
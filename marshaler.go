@@ -0,0 +1,140 @@
+package jwalk
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// Marshalers returns the full set of jwalk marshalers. These allow encoding:
+//   - Document: ordered object encoding (entries emitted in slice order)
+//   - Array: ordered array encoding
+//
+// A nil Document or Array encodes as null, while an empty non-nil one encodes
+// as {} or [] respectively. WithEmptyAsNull makes empty values encode as null
+// too.
+//
+// Values nested inside a Document or Array are encoded through the same
+// marshalers, so ordering is preserved throughout the tree.
+func Marshalers(reg *Registry) *json.Marshalers {
+	return marshalers(reg, reg.directiveEncoding)
+}
+
+// marshalers is Marshalers, encoding values of round-trip directive types as
+// sentinels if directives is set.
+func marshalers(reg *Registry, directives bool) *json.Marshalers {
+	return json.JoinMarshalers(
+		marshalDocument(reg),
+		marshalCollection(reg),
+		marshalSecret(reg), // nil unless WithSecretsRevealed
+		marshalDirective(reg, directives),
+	)
+}
+
+// marshalSecret encodes a Secret as its plaintext string (WithSecretsRevealed),
+// taking precedence over Secret's MarshalJSONTo method, which encodes its
+// reference.
+func marshalSecret(reg *Registry) *json.Marshalers {
+	if !reg.revealSecrets {
+		return nil
+	}
+	return json.MarshalToFunc(func(enc *jsontext.Encoder, s Secret) error {
+		return enc.WriteToken(jsontext.String(s.value))
+	})
+}
+
+// marshalDirective encodes a value whose type a round-trip directive produces
+// as that directive's sentinel object (WithDirectiveEncoding), or returns nil
+// unless enabled. The directive's marshaler writes to an encoder of its own,
+// without this marshaler, so that it may encode the value by default means
+// without recursing.
+func marshalDirective(reg *Registry, enabled bool) *json.Marshalers {
+	if !enabled {
+		return nil
+	}
+	plain := json.WithMarshalers(json.JoinMarshalers(marshalDocument(reg), marshalCollection(reg)))
+	return json.MarshalToFunc(func(enc *jsontext.Encoder, p any) error {
+		// a func for an interface type is handed a pointer to each value
+		v := reflect.ValueOf(p).Elem()
+		d := reg.encoderFor(v.Type())
+		if d == nil {
+			return json.SkipFunc
+		}
+
+		var buf bytes.Buffer
+		if err := d.encode(jsontext.NewEncoder(&buf, enc.Options(), plain), v.Interface()); err != nil {
+			return fmt.Errorf("directive %q: %w", d.name, err)
+		}
+		if d.mode == ObjectMode {
+			return enc.WriteValue(buf.Bytes())
+		}
+
+		if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+			return fmt.Errorf("write object open: %w", err)
+		}
+		if err := enc.WriteToken(jsontext.String("$" + d.name)); err != nil {
+			return fmt.Errorf("write object key %q: %w", "$"+d.name, err)
+		}
+		if err := enc.WriteValue(buf.Bytes()); err != nil {
+			return fmt.Errorf("directive %q: %w", d.name, err)
+		}
+		if err := enc.WriteToken(jsontext.EndObject); err != nil {
+			return fmt.Errorf("write object close: %w", err)
+		}
+		return nil
+	})
+}
+
+// marshalDocument encodes a Document as a JSON object, preserving entry order.
+func marshalDocument(reg *Registry) *json.Marshalers {
+	return json.MarshalToFunc(func(enc *jsontext.Encoder, d Document) error {
+		if d == nil || (len(d) == 0 && reg.emptyAsNull) {
+			return enc.WriteToken(jsontext.Null)
+		}
+
+		if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+			return fmt.Errorf("write object open: %w", err)
+		}
+
+		for _, e := range d {
+			if err := enc.WriteToken(jsontext.String(e.Key)); err != nil {
+				return fmt.Errorf("write object key %q: %w", e.Key, err)
+			}
+			if err := json.MarshalEncode(enc, e.Value); err != nil {
+				return fmt.Errorf("write object value for key %q: %w", e.Key, err)
+			}
+		}
+
+		if err := enc.WriteToken(jsontext.EndObject); err != nil {
+			return fmt.Errorf("write object close: %w", err)
+		}
+		return nil
+	})
+}
+
+// marshalCollection encodes an Array as a JSON array.
+func marshalCollection(reg *Registry) *json.Marshalers {
+	return json.MarshalToFunc(func(enc *jsontext.Encoder, a Array) error {
+		if a == nil || (len(a) == 0 && reg.emptyAsNull) {
+			return enc.WriteToken(jsontext.Null)
+		}
+
+		if err := enc.WriteToken(jsontext.BeginArray); err != nil {
+			return fmt.Errorf("write array open: %w", err)
+		}
+
+		for _, elem := range a {
+			if err := json.MarshalEncode(enc, elem); err != nil {
+				return fmt.Errorf("write array element: %w", err)
+			}
+		}
+
+		if err := enc.WriteToken(jsontext.EndArray); err != nil {
+			return fmt.Errorf("write array close: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,122 @@
+package jwalk_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestDirectiveEncodingRoundTrip(t *testing.T) {
+	reg, err := jwalk.NewRegistry(jwalk.WithStdlib(), jwalk.WithDirectiveEncoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2023, 10, 5, 14, 0, 0, 123456789, time.FixedZone("", 2*60*60))
+	type event struct {
+		At    time.Time
+		After time.Duration
+	}
+	in := jwalk.Document{
+		{Key: "at", Value: when},
+		{Key: "timeout", Value: 90 * time.Second},
+		{Key: "steps", Value: jwalk.Array{time.Duration(0), -time.Millisecond, when.UTC()}},
+		{Key: "event", Value: event{At: when, After: time.Hour}},
+	}
+	const want = `{"at":{"$std.time":"2023-10-05T14:00:00.123456789+02:00"},` +
+		`"timeout":{"$std.duration":"1m30s"},` +
+		`"steps":[{"$std.duration":"0s"},{"$std.duration":"-1ms"},{"$std.time":"2023-10-05T12:00:00.123456789Z"}],` +
+		`"event":{"At":{"$std.time":"2023-10-05T14:00:00.123456789+02:00"},"After":{"$std.duration":"1h0m0s"}}}`
+	b, err := reg.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Fatalf("Marshal = %s\nwant      %s", b, want)
+	}
+
+	var out jwalk.Document
+	if err := reg.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := out[0].Value.(time.Time); !got.Equal(when) {
+		t.Errorf("at = %v, want %v", got, when)
+	}
+	if got := out[1].Value; got != 90*time.Second {
+		t.Errorf("timeout = %v, want 1m30s", got)
+	}
+	steps := out[2].Value.(jwalk.Array)
+	if steps[0] != time.Duration(0) || steps[1] != -time.Millisecond || !steps[2].(time.Time).Equal(when) {
+		t.Errorf("steps = %v", steps)
+	}
+
+	ev := out[3].Value.(jwalk.Document)
+	if !ev[0].Value.(time.Time).Equal(when) || ev[1].Value != time.Hour {
+		t.Errorf("event = %v", ev)
+	}
+
+	// the decoded values encode as they did before
+	if again, err := reg.Marshal(out); err != nil || string(again) != want {
+		t.Errorf("Marshal(Unmarshal(Marshal(in))) = %s (%v)", again, err)
+	}
+
+	// without the option, a time.Time encodes as the json package encodes it
+	plain, err := jwalk.NewRegistry(jwalk.WithStdlib())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := plain.Marshal(jwalk.Document{{Key: "at", Value: when}}); err != nil || string(got) != `{"at":"2023-10-05T14:00:00.123456789+02:00"}` {
+		t.Errorf("Marshal without WithDirectiveEncoding = %s (%v)", got, err)
+	}
+}
+
+func TestMarshalNilAndEmpty(t *testing.T) {
+	type holder struct {
+		D jwalk.Document
+		A jwalk.Array
+	}
+	tests := []struct {
+		name string
+		in   any
+		want string // default
+		null string // WithEmptyAsNull
+	}{
+		{"nil Document", jwalk.Document(nil), `null`, `null`},
+		{"empty Document", jwalk.Document{}, `{}`, `null`},
+		{"nil Array", jwalk.Array(nil), `null`, `null`},
+		{"empty Array", jwalk.Array{}, `[]`, `null`},
+		{"nested", jwalk.Document{{Key: "n", Value: jwalk.Document(nil)}, {Key: "e", Value: jwalk.Document{}}, {Key: "a", Value: jwalk.Array{jwalk.Array{}, jwalk.Array(nil)}}},
+			`{"n":null,"e":{},"a":[[],null]}`, `{"n":null,"e":null,"a":[null,null]}`},
+		{"struct fields", holder{D: jwalk.Document{}}, `{"D":{},"A":null}`, `{"D":null,"A":null}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []struct {
+				opts []jwalk.Option
+				want string
+			}{{nil, tt.want}, {[]jwalk.Option{jwalk.WithEmptyAsNull()}, tt.null}} {
+				reg, err := jwalk.NewRegistry(c.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := reg.Marshal(tt.in); err != nil || string(got) != c.want {
+					t.Errorf("Marshal (%d options) = %s (%v), want %s", len(c.opts), got, err, c.want)
+				}
+			}
+		})
+	}
+
+	// decoding distinguishes them the same way
+	reg, err := jwalk.NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d jwalk.Document
+	if err := reg.Unmarshal([]byte(`{}`), &d); err != nil || d == nil || len(d) != 0 {
+		t.Errorf("Unmarshal({}) = %#v (%v), want empty non-nil Document", d, err)
+	}
+	if err := reg.Unmarshal([]byte(`null`), &d); err != nil || d != nil {
+		t.Errorf("Unmarshal(null) = %#v (%v), want nil Document", d, err)
+	}
+}
@@ -0,0 +1,43 @@
+package jwalk
+
+import "slices"
+
+// ApplyMergePatch applies patch to target as a JSON Merge Patch (RFC 7386)
+// and returns the result. target and patch are not modified.
+//
+// For each entry in patch: a nil (JSON null) value deletes the key from
+// target, a Document value is merged recursively into target's value for the
+// key (or into an empty Document if that is not a Document), and any other
+// value, Arrays included, replaces target's value. Keys already in target keep
+// their position; new keys are appended in patch order.
+func ApplyMergePatch(target, patch Document) Document {
+	out := slices.Clone(target)
+	if out == nil {
+		out = Document{}
+	}
+	for _, p := range patch {
+		i := slices.IndexFunc(out, func(e Entry) bool { return e.Key == p.Key })
+
+		if p.Value == nil {
+			if i >= 0 {
+				out = slices.DeleteFunc(out, func(e Entry) bool { return e.Key == p.Key })
+			}
+			continue
+		}
+
+		v := p.Value
+		if pd, ok := p.Value.(Document); ok {
+			var cur Document
+			if i >= 0 {
+				cur, _ = out[i].Value.(Document)
+			}
+			v = ApplyMergePatch(cur, pd)
+		}
+		if i >= 0 {
+			out[i].Value = v
+		} else {
+			out = append(out, Entry{Key: p.Key, Value: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package jwalk_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	reg, err := jwalk.NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// repeated keys are kept, as in any Document
+	decode := func(t *testing.T, s string) jwalk.Document {
+		t.Helper()
+		if s == "" {
+			return nil
+		}
+		var d jwalk.Document
+		if err := reg.Unmarshal([]byte(s), &d, jsontext.AllowDuplicateNames(true)); err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	for _, tt := range []struct {
+		name                string
+		target, patch, want string // "" for a nil Document
+	}{
+		// from RFC 7386, Appendix A, less those whose patch is not an object
+		{"replace", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"delete", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"delete one", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replace array", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"array replaces", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"nested", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"arrays not merged", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"existing nulls kept", `{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{"new nested nulls dropped", `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+
+		{"order kept", `{"z":1,"a":2,"m":3}`, `{"n":4,"a":5}`, `{"z":1,"a":5,"m":3,"n":4}`},
+		{"nil target", "", `{"a":{"b":1}}`, `{"a":{"b":1}}`},
+		{"nil patch", `{"a":1}`, "", `{"a":1}`},
+		{"delete missing", `{"a":1}`, `{"b":null}`, `{"a":1}`},
+		{"merge into non-object", `{"a":[1]}`, `{"a":{"b":null,"c":2}}`, `{"a":{"c":2}}`},
+		{"repeated keys deleted", `{"a":1,"b":2,"a":3}`, `{"a":null}`, `{"b":2}`},
+		{"repeated keys replace the first", `{"a":1,"a":3}`, `{"a":2}`, `{"a":2,"a":3}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			target, patch := decode(t, tt.target), decode(t, tt.patch)
+			got, err := reg.Marshal(jwalk.ApplyMergePatch(target, patch), jsontext.AllowDuplicateNames(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("ApplyMergePatch = %s, want %s", got, tt.want)
+			}
+			// neither input is modified
+			if !reflect.DeepEqual(target, decode(t, tt.target)) || !reflect.DeepEqual(patch, decode(t, tt.patch)) {
+				t.Errorf("ApplyMergePatch modified its inputs: target %v, patch %v", target, patch)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package jwalk
+
+// NormalizeIntegers returns a copy of root in which every float64 with no
+// fractional part, at any depth within Documents and Arrays, is replaced by
+// the equivalent int64. It is meant for handing decoded data to code that
+// type-switches on int64. root is not modified.
+//
+// Numbers decode as float64, which represents integers exactly only up to
+// 2^53 in magnitude; larger inputs may already have been rounded during
+// decoding, and NormalizeIntegers converts the rounded value. Whole values
+// outside the int64 range are left as float64.
+func NormalizeIntegers(root any) any {
+	switch val := root.(type) {
+	case float64:
+		if n, ok := floatToInt(val); ok {
+			return n
+		}
+		return val
+
+	case Document:
+		if val == nil {
+			return val
+		}
+		out := make(Document, len(val))
+		for i, e := range val {
+			out[i] = Entry{Key: e.Key, Value: NormalizeIntegers(e.Value)}
+		}
+		return out
+
+	case Array:
+		if val == nil {
+			return val
+		}
+		out := make(Array, len(val))
+		for i, elem := range val {
+			out[i] = NormalizeIntegers(elem)
+		}
+		return out
+
+	default:
+		return root
+	}
+}
@@ -0,0 +1,117 @@
+package jwalk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PathError records where in the decoded tree a directive failed. It is
+// returned by Registry.Unmarshal and the other Registry decode methods, and
+// can be retrieved from errors returned by the json package with errors.As.
+type PathError struct {
+	Pointer string // JSON Pointer (RFC 6901) to the failing value, e.g. "/config/events/2/created"
+	Err     error
+}
+
+// Error renders the path in a dotted form, e.g.
+//
+//	config.events[2].created: directive "std.time": parsing time ...
+//
+// Numeric tokens are rendered as array indexes, so an object key such as "2"
+// is indistinguishable from an index here; use Pointer when that matters.
+func (e *PathError) Error() string {
+	return formatPath(e.Pointer) + ": " + e.Err.Error()
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// formatPath renders a JSON Pointer as a dotted path with bracketed indexes.
+func formatPath(ptr string) string {
+	if ptr == "" {
+		return "(root)"
+	}
+	var b strings.Builder
+	for tok := range strings.SplitSeq(ptr[1:], "/") {
+		tok = pointerUnescaper.Replace(tok)
+		if isIndex(tok) {
+			b.WriteByte('[')
+			b.WriteString(tok)
+			b.WriteByte(']')
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(tok)
+	}
+	return b.String()
+}
+
+func isIndex(tok string) bool {
+	if tok == "" {
+		return false
+	}
+	for _, c := range tok {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// directiveError locates a directive failure at the object holding the
+// sentinel key at keyPtr. If err already carries a *PathError (a value nested
+// inside the directive's input failed first) that is returned instead.
+func directiveError(keyPtr string, err error) error {
+	var pe *PathError
+	if errors.As(err, &pe) {
+		return pe
+	}
+	return &PathError{Pointer: parentPointer(keyPtr), Err: err}
+}
+
+// rawDirectiveError locates a failure of a directive that ran on its own
+// decoder over the value at base (see decodeState.invokeRaw): nested paths,
+// relative to that value, are rebased onto it, and errors without a path are
+// located at the object at objPtr.
+func rawDirectiveError(base, objPtr string, err error) error {
+	var pe *PathError
+	if errors.As(err, &pe) {
+		return &PathError{Pointer: base + pe.Pointer, Err: pe.Err}
+	}
+	return &PathError{Pointer: objPtr, Err: err}
+}
+
+// flattenPath renders the location of a *PathError within err into its
+// message, for a failure within a document other than the input, such as
+// embedded JSON or an included file, whose paths are not paths in the input.
+// The error is then located at the directive that decoded that document.
+func flattenPath(err error) error {
+	var pe *PathError
+	if errors.As(err, &pe) {
+		return fmt.Errorf("%s: %w", formatPath(pe.Pointer), pe.Err)
+	}
+	return err
+}
+
+// nestedError passes through the *PathError of a failed nested value, so the
+// innermost location is reported once rather than wrapped at every level.
+// Errors without a path are returned unchanged.
+func nestedError(err error) error {
+	var pe *PathError
+	if errors.As(err, &pe) {
+		return pe
+	}
+	return err
+}
+
+// parentPointer returns the pointer to the value containing ptr.
+func parentPointer(ptr string) string {
+	if i := strings.LastIndexByte(ptr, '/'); i >= 0 {
+		return ptr[:i]
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package jwalk
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// Position identifies a location in the source input.
+type Position struct {
+	Offset int64 // byte offset, starting at 0
+	Line   int   // line number, starting at 1
+	Column int   // byte column, starting at 1
+}
+
+// Positions maps the JSON Pointer (RFC 6901) of each decoded object entry,
+// e.g. "/config/retry_after", to the position of its key in the source.
+type Positions map[string]Position
+
+// UnmarshalWithPositions decodes in like Unmarshal and additionally reports
+// the source position of every decoded Document entry's key, for tools such
+// as editors and linters that need to point back into the input.
+//
+// Entries of directive sentinel objects are not recorded, since they do not
+// appear in the decoded tree. Position tracking applies only to this call.
+func (r *Registry) UnmarshalWithPositions(in []byte, out any, opts ...json.Options) (Positions, error) {
+	st := r.newState()
+	st.positions = make(map[string]int64)
+	if err := r.decodeSeeded(in, out, st, opts...); err != nil {
+		return nil, err
+	}
+
+	// index line starts for offset -> line/column conversion
+	lines := []int64{0}
+	for i, c := range in {
+		if c == '\n' {
+			lines = append(lines, int64(i+1))
+		}
+	}
+
+	pos := make(Positions, len(st.positions))
+	for ptr, off := range st.positions {
+		// the recorded offset precedes any whitespace or separator before the key
+		for off < int64(len(in)) && isKeyPrefix(in[off]) {
+			off++
+		}
+		line, found := slices.BinarySearch(lines, off)
+		if !found {
+			line--
+		}
+		pos[ptr] = Position{Offset: off, Line: line + 1, Column: int(off-lines[line]) + 1}
+	}
+	return pos, nil
+}
+
+// isKeyPrefix reports whether c may appear between the previous token and an
+// object key.
+func isKeyPrefix(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', ',':
+		return true
+	}
+	return false
+}
+
+// recordPosition records the entry just read at dec's current pointer, if
+// position tracking is enabled. off is the input offset before its key.
+func (st *decodeState) recordPosition(dec *jsontext.Decoder, off int64) {
+	if st.positions != nil {
+		st.positions[string(dec.StackPointer())] = off
+	}
+}
+
+// dropPositions forgets the positions recorded beneath the object at ptr, e.g.
+// for members of an object that turned out to be a directive sentinel.
+func (st *decodeState) dropPositions(ptr string) {
+	if st.positions == nil {
+		return
+	}
+	prefix := ptr + "/"
+	for k := range st.positions {
+		if strings.HasPrefix(k, prefix) {
+			delete(st.positions, k)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package jwalk_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestUnmarshalWithPositions(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    []jwalk.Option
+		in      string
+		want    jwalk.Positions
+		wantErr string
+	}{
+		{"flat", nil, `{"a": 1, "bc": 2}`, jwalk.Positions{
+			"/a":  {Offset: 1, Line: 1, Column: 2},
+			"/bc": {Offset: 9, Line: 1, Column: 10},
+		}, ""},
+		{"nested", nil, "{\n\t\"cfg\": {\n\t\t\"x\": [1, {\"y\": true}]\n\t},\n\t\"z\": null\n}", jwalk.Positions{
+			"/cfg":       {Offset: 3, Line: 2, Column: 2},
+			"/cfg/x":     {Offset: 14, Line: 3, Column: 3},
+			"/cfg/x/1/y": {Offset: 24, Line: 3, Column: 13},
+			"/z":         {Offset: 41, Line: 5, Column: 2},
+		}, ""},
+		{"escaped keys", nil, "{\"a/b\": {\"~\": 1}}", jwalk.Positions{
+			"/a~1b":    {Offset: 1, Line: 1, Column: 2},
+			"/a~1b/~0": {Offset: 9, Line: 1, Column: 10},
+		}, ""},
+		{"CRLF", nil, "{\r\n\"a\": 1,\r\n\"b\": 2}", jwalk.Positions{
+			"/a": {Offset: 3, Line: 2, Column: 1},
+			"/b": {Offset: 12, Line: 3, Column: 1},
+		}, ""},
+		{"sentinel members dropped", []jwalk.Option{jwalk.WithStdlib()}, `{"t": {"$std.duration": "1s"}, "u": 1}`, jwalk.Positions{
+			"/t": {Offset: 1, Line: 1, Column: 2},
+			"/u": {Offset: 31, Line: 1, Column: 32},
+		}, ""},
+		{"sentinel scan", []jwalk.Option{jwalk.WithStdlib(), jwalk.WithSentinelScan()}, `{"t": {"a": {"b": 1}, "$std.duration": "1s"}}`, jwalk.Positions{
+			"/t": {Offset: 1, Line: 1, Column: 2},
+		}, ""},
+		{"empty", nil, `{}`, jwalk.Positions{}, ""},
+		{"invalid", nil, "{\"a\": 1,\n}", nil, "invalid character ','"},
+		{"directive error", []jwalk.Option{jwalk.WithStdlib()}, `{"t": {"$std.duration": "x"}}`, nil, `t: directive "std.duration"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc jwalk.Document
+			got, err := reg.UnmarshalWithPositions([]byte(tt.in), &doc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UnmarshalWithPositions = %v, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("UnmarshalWithPositions = %v, want %v", got, tt.want)
+			}
+			// each position is that of its key
+			for ptr, pos := range got {
+				name := ptr[strings.LastIndexByte(ptr, '/')+1:]
+				name = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+				if !strings.HasPrefix(tt.in[pos.Offset:], `"`+name+`"`) {
+					t.Errorf("%s at %d: %.10q", ptr, pos.Offset, tt.in[pos.Offset:])
+				}
+			}
+		})
+	}
+}
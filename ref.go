@@ -0,0 +1,164 @@
+package jwalk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// RefDirective is a Directive registered as "ref" that decodes values of the
+// form:
+//
+//	{"$ref": "/defaults/timeout"}
+//
+// into a Ref placeholder holding the JSON Pointer (RFC 6901). References are
+// resolved against the whole decoded tree by a second pass, ResolveRefs. It is
+// not part of the standard set and must be registered explicitly.
+var RefDirective = NewRefDirective("ref")
+
+// NewRefDirective constructs a reference Directive registered under the given
+// name. See RefDirective.
+func NewRefDirective(name string) *Directive {
+	return NewDirective(name, unmarshalRef)
+}
+
+// Ref is an unresolved intra-document reference produced by RefDirective.
+type Ref struct {
+	Pointer string // JSON Pointer (RFC 6901) into the document root
+}
+
+func unmarshalRef(dec *jsontext.Decoder) (Ref, error) {
+	var ptr string
+	if err := json.UnmarshalDecode(dec, &ptr); err != nil {
+		return Ref{}, err
+	}
+	if ptr != "" && ptr[0] != '/' {
+		return Ref{}, fmt.Errorf("invalid JSON pointer %q (must be empty or start with /)", ptr)
+	}
+	return Ref{Pointer: ptr}, nil
+}
+
+// ResolveRefs returns a copy of root in which every Ref has been replaced by
+// the value its pointer refers to within root. Referenced values may
+// themselves contain references. root is not modified; resolved values are
+// shared between the places that reference them.
+//
+// A reference that points at itself, directly or through other references, is
+// reported as an error, as is a pointer that does not resolve.
+func ResolveRefs(root any) (any, error) {
+	r := &refResolver{
+		root:     root,
+		visiting: make(map[string]bool),
+		resolved: make(map[string]any),
+	}
+	return r.resolve(root)
+}
+
+// refResolver resolves references against a single root, memoizing each
+// resolved pointer and tracking the pointers currently being resolved to
+// detect cycles.
+type refResolver struct {
+	root     any
+	visiting map[string]bool
+	resolved map[string]any
+}
+
+func (r *refResolver) resolve(v any) (any, error) {
+	switch val := v.(type) {
+	case Ref:
+		return r.deref(val.Pointer)
+
+	case Document:
+		out := make(Document, len(val))
+		for i, e := range val {
+			rv, err := r.resolve(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = Entry{Key: e.Key, Value: rv}
+		}
+		return out, nil
+
+	case Array:
+		out := make(Array, len(val))
+		for i, elem := range val {
+			rv, err := r.resolve(elem)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rv
+		}
+		return out, nil
+
+	default:
+		return v, nil
+	}
+}
+
+// deref resolves a pointer to its fully resolved value.
+func (r *refResolver) deref(ptr string) (any, error) {
+	if v, ok := r.resolved[ptr]; ok {
+		return v, nil
+	}
+	if r.visiting[ptr] {
+		return nil, fmt.Errorf("cyclic reference %q", ptr)
+	}
+	r.visiting[ptr] = true
+	defer delete(r.visiting, ptr)
+
+	target, err := r.lookup(ptr)
+	if err != nil {
+		return nil, err
+	}
+	v, err := r.resolve(target)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[ptr] = v
+	return v, nil
+}
+
+// pointerUnescaper decodes the ~1 and ~0 escapes in a JSON Pointer token.
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// lookup walks ptr from the root, dereferencing any Ref met along the way.
+func (r *refResolver) lookup(ptr string) (any, error) {
+	cur := r.root
+	if ptr == "" {
+		return cur, nil
+	}
+
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		tok = pointerUnescaper.Replace(tok)
+
+		if ref, ok := cur.(Ref); ok {
+			v, err := r.deref(ref.Pointer)
+			if err != nil {
+				return nil, err
+			}
+			cur = v
+		}
+
+		switch node := cur.(type) {
+		case Document:
+			v, ok := node.lookup(tok)
+			if !ok {
+				return nil, fmt.Errorf("reference %q: key %q not found", ptr, tok)
+			}
+			cur = v
+		case Array:
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx >= len(node) || (len(tok) > 1 && tok[0] == '0') {
+				return nil, fmt.Errorf("reference %q: invalid array index %q", ptr, tok)
+			}
+			cur = node[idx]
+		default:
+			return nil, fmt.Errorf("reference %q: cannot traverse %T at /%s", ptr, cur, strings.Join(tokens[:i], "/"))
+		}
+	}
+	return cur, nil
+}
@@ -1,7 +1,14 @@
 package jwalk
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,15 +20,97 @@ import (
 // Registry holds a set of named directive functions for decoding special JSON
 // objects. It is safe for concurrent use.
 //
+// Lookups are lock-free: the registered directives are held in an immutable
+// snapshot that writers copy, modify and publish atomically. This favours the
+// typical "configure once, decode many" usage, at the cost of slower writes.
+//
 // Directives may be registered with fully qualified names (e.g. "std.time") or
 // with bare names (e.g. "time"). Bare names can be used for lookup only if they
 // are unambiguous. Once two directives share the same short name, callers must
 // use the fully qualified name.
 type Registry struct {
-	mu      sync.RWMutex
-	entries map[string]*Directive // full names (may include namespace prefix, e.g. ns.name)
-	shorts  map[string][]string   // short name -> list of fully qualified names
-	sepByte byte                  // single-character namespace separator (default '.')
+	mu      sync.Mutex                       // serializes writers
+	snap    atomic.Pointer[registrySnapshot] // current directive set
+	sepByte byte                             // single-character namespace separator (default '.')
+
+	internKeys        bool // intern object keys per decode (WithStringInterning)
+	requireObjectRoot bool // reject non-object roots when decoding into any (WithRequireObjectRoot)
+	maxTotalValues    int  // per-decode budget of decoded values; 0 means unlimited (WithMaxTotalValues)
+	maxStringLen      int  // longest string value or object key in bytes; 0 means unlimited (WithMaxStringLen)
+	scratch           bool // give each decode a scratch map for directives (WithScratch)
+
+	directivesDisabled bool // decode sentinels as plain objects (WithDirectivesDisabled)
+
+	allowed          map[string]bool // directives that may be dispatched; nil allows all (WithAllowedDirectives)
+	denied           map[string]bool // directives that may not be dispatched (WithDeniedDirectives)
+	disallowedAsData bool            // decode sentinels of disallowed directives as data (WithDisallowedAsData)
+	emptyAsLiteral   bool            // treat a bare "$" key as a plain key (WithEmptyDirectiveAsLiteral)
+
+	directiveHook func(name string, result any, err error) // called after each directive runs (WithDirectiveHook)
+
+	nestedAsData bool         // hide the Registry's unmarshalers from directives (WithNestedSentinelsAsData)
+	plainOpts    json.Options // options decoding directive values under nestedAsData
+
+	sentinelScan     bool     // look for "$" keys at any position (WithSentinelScan)
+	sentinelPriority []string // directive names in precedence order (WithSentinelScan)
+
+	emptyAsNull bool // encode empty Document/Array as null (WithEmptyAsNull)
+
+	directiveEncoding bool // encode values of round-trip directive types as sentinels (WithDirectiveEncoding)
+
+	revealSecrets bool // encode Secret values in plaintext (WithSecretsRevealed)
+
+	rejectUnordered bool // reject map[string]any targets for objects (WithRejectUnorderedMaps)
+
+	objCap int // initial entry capacity of decoded Documents (WithInitialCapacity)
+	arrCap int // initial element capacity of decoded Arrays (WithInitialCapacity)
+
+	valueTransform func(any) (any, error) // applied to decoded primitives (WithValueTransform)
+
+	plainArrays bool // decode arrays into any as []any rather than Array (WithPlainArrays)
+
+	bomStripping bool // accept a byte order mark in reader input (WithBOMStripping)
+
+	collectErrors bool // continue past recoverable decode errors (WithErrorCollection)
+
+	shared        *json.Unmarshalers // Unmarshalers(r), built once
+	unmarshalOpts json.Options       // json.WithUnmarshalers(shared)
+
+	stateless *decodeState // shared state used when no per-decode state is needed
+	seeded    atomic.Int32 // number of in-flight decodes with pre-registered state
+}
+
+// registrySnapshot is an immutable view of the registered directives. It must
+// not be modified once published; writers modify a clone instead.
+//
+// Keeping entries and its indexes in the same snapshot means readers always
+// see them in a mutually consistent state: a directive is either present in
+// all of them or in none.
+type registrySnapshot struct {
+	entries map[string]*Directive     // full names (may include namespace prefix, e.g. ns.name)
+	shorts  map[string][]string       // short name -> list of fully qualified names
+	types   map[reflect.Type][]string // result type -> fully qualified names, in registration order
+
+	stateful int // number of entries that need per-decode state
+}
+
+// clone returns a copy of s that may be modified without affecting s.
+func (s *registrySnapshot) clone() *registrySnapshot {
+	shorts := make(map[string][]string, len(s.shorts))
+	for k, v := range s.shorts {
+		// clip so appends to the copy never write into s's backing array
+		shorts[k] = slices.Clip(v)
+	}
+	types := make(map[reflect.Type][]string, len(s.types))
+	for k, v := range s.types {
+		types[k] = slices.Clip(v)
+	}
+	return &registrySnapshot{
+		entries:  maps.Clone(s.entries),
+		shorts:   shorts,
+		types:    types,
+		stateful: s.stateful,
+	}
 }
 
 // RegistryOption represents a registry construction option.
@@ -31,9 +120,351 @@ type Registry struct {
 // NewRegistry.
 type RegistryOption func(*RegistryOptions) error
 
+// Option is an argument to NewRegistry or With: a RegistryOption, as returned
+// by the With* option constructors, or a *Directive, so directives and
+// configuration can be passed together:
+//
+//	reg, err := jwalk.NewRegistry(jwalk.StdTimeDirective, jwalk.StdDurationDirective, jwalk.WithSentinelScan())
+type Option interface {
+	applyRegistry(*RegistryOptions) error
+}
+
+func (f RegistryOption) applyRegistry(o *RegistryOptions) error {
+	if f == nil {
+		return nil
+	}
+	return f(o)
+}
+
+// applyRegistry registers d, making *Directive usable as an Option.
+// Passing the same *Directive more than once registers it once.
+func (d *Directive) applyRegistry(o *RegistryOptions) error {
+	if d == nil {
+		return errors.New("nil directive")
+	}
+	o.addDirective(d)
+	return nil
+}
+
+// addDirective adds d to the directives to register, unless already present.
+func (o *RegistryOptions) addDirective(d *Directive) {
+	if !slices.Contains(o.Directives, d) {
+		o.Directives = append(o.Directives, d)
+	}
+}
+
+// WithStdlib registers the standard directives returned by Stdlib, the usual
+// starting point:
+//
+//	reg, err := jwalk.NewRegistry(jwalk.WithStdlib(), jwalk.EnvDirective)
+//
+// Standard directives also passed individually in the same call are
+// registered once.
+func WithStdlib() RegistryOption {
+	return func(o *RegistryOptions) error {
+		for _, d := range Stdlib() {
+			o.addDirective(d)
+		}
+		return nil
+	}
+}
+
+// WithDirective returns an option registering d.
+//
+// Deprecated: *Directive is itself an Option; pass d directly.
 func WithDirective(d *Directive) RegistryOption {
+	return d.applyRegistry
+}
+
+// WithStringInterning makes each decode reuse a single string for every
+// occurrence of the same object key, reducing memory for large homogeneous
+// datasets (e.g. arrays of records sharing a handful of keys).
+//
+// The interning table is scoped to a single decode and discarded afterwards.
+func WithStringInterning() RegistryOption {
 	return func(o *RegistryOptions) error {
-		o.Directives = append(o.Directives, d)
+		o.StringInterning = true
+		return nil
+	}
+}
+
+// WithRequireObjectRoot makes decoding into any fail unless the top-level JSON
+// value is an object, catching e.g. a config loader handed a bare array or
+// scalar. The error names the kind of value found instead. Only the root of
+// the input is checked, not that of a file included by NewIncludeDirective or
+// of JSON a directive decodes from a string, such as std.json.
+func WithRequireObjectRoot() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.RequireObjectRoot = true
+		return nil
+	}
+}
+
+// WithMaxTotalValues bounds the total number of values (objects, arrays and
+// primitives, at any depth) a single decode may produce. Decoding fails with
+// an error wrapping ErrMaxTotalValues once the budget is exhausted, defending
+// against flat-but-enormous untrusted input. n must be positive.
+//
+// Through the Registry's decode methods, the budget covers the whole call,
+// including values the json package decodes itself, such as the elements of
+// a []any or the fields of a struct. Decoding with json.Unmarshal and the
+// Registry's Options counts only the values jwalk decodes, with a budget for
+// each outermost value it decodes.
+func WithMaxTotalValues(n int) RegistryOption {
+	return func(o *RegistryOptions) error {
+		if n <= 0 {
+			return fmt.Errorf("max total values must be positive, got %d", n)
+		}
+		o.MaxTotalValues = n
+		return nil
+	}
+}
+
+// WithMaxStringLen bounds the length in bytes of every string value and object
+// key in objects and arrays decoded by jwalk. Decoding fails with a *PathError
+// wrapping ErrMaxStringLen at the first longer string, so a single oversized
+// string cannot be retained even when value counts and depth are bounded.
+// Strings consumed by directives are not checked. n must be positive.
+func WithMaxStringLen(n int) RegistryOption {
+	return func(o *RegistryOptions) error {
+		if n <= 0 {
+			return fmt.Errorf("max string length must be positive, got %d", n)
+		}
+		o.MaxStringLen = n
+		return nil
+	}
+}
+
+// WithDirectivesDisabled turns off directive dispatch entirely: every object,
+// "$"-prefixed keys included, decodes as a plain Document, and
+// InvokeDirective fails. Unlike an empty Registry, this holds whatever
+// directives are registered, e.g. for a registry derived with With from a
+// shared base, making it a safe posture for fully untrusted input where
+// directives such as $include or $env must never run.
+func WithDirectivesDisabled() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.NoDirectives = true
+		return nil
+	}
+}
+
+// WithAllowedDirectives restricts decoding to dispatching only the named
+// directives, given by their fully qualified names (e.g. "std.time"); a
+// sentinel naming any other directive fails to decode, or is decoded as data
+// under WithDisallowedAsData. With no names, no directive may be dispatched.
+// It replaces any allowlist set earlier, including a base registry's in With,
+// e.g. to reuse a rich registry for less-trusted input:
+//
+//	restricted, err := jwalk.With(reg, jwalk.WithAllowedDirectives("std.time", "std.duration"))
+//
+// Registry.UnmarshalAllowing restricts a single call instead.
+func WithAllowedDirectives(names ...string) RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.AllowedDirectives = append([]string{}, names...)
+		return nil
+	}
+}
+
+// WithDeniedDirectives forbids dispatching the named directives, given by
+// their fully qualified names, e.g. "include" or "env". A sentinel naming one
+// fails to decode, or is decoded as data under WithDisallowedAsData. Denied
+// names accumulate, including over a base registry's in With, and take
+// precedence over WithAllowedDirectives.
+func WithDeniedDirectives(names ...string) RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.DeniedDirectives = append(o.DeniedDirectives, names...)
+		return nil
+	}
+}
+
+// WithDisallowedAsData makes a sentinel naming a directive that is not
+// allowed (see WithAllowedDirectives, WithDeniedDirectives and
+// Registry.UnmarshalAllowing) decode as a plain Document instead of failing.
+func WithDisallowedAsData() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.DisallowedAsData = true
+		return nil
+	}
+}
+
+// WithDirectiveHook registers fn to be called after each directive runs, with
+// the directive's fully qualified name (even when the sentinel used its short
+// name; an alias reports its own name), its result, and the error it
+// returned, if any, e.g. to log dispatches or count failures:
+//
+//	jwalk.WithDirectiveHook(func(name string, result any, err error) {
+//	    slog.Debug("directive", "name", name, "err", err)
+//	})
+//
+// fn observes only; it cannot change the result. Names that fail to resolve
+// never run a directive and are not reported. fn may be called from
+// concurrent decodes, so it must be safe for concurrent use.
+func WithDirectiveHook(fn func(name string, result any, err error)) RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.DirectiveHook = fn
+		return nil
+	}
+}
+
+// WithNestedSentinelsAsData stops sentinels nested within a directive's value
+// from being dispatched (see Directive): each directive reads its value
+// through a decoder whose unmarshalers decode objects and arrays as plain
+// Documents and Arrays, so {"$outer": {"$inner": 1}} hands $outer the
+// Document {"$inner": 1} however it decodes it. This replaces any unmarshalers
+// passed in the decode options for the directive's value, and the value is
+// buffered before the directive runs.
+func WithNestedSentinelsAsData() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.NestedAsData = true
+		return nil
+	}
+}
+
+// WithEmptyDirectiveAsLiteral makes a bare "$" key, as in {"$": 1}, an
+// ordinary object key rather than a sentinel. By default such a key is a
+// sentinel with an empty directive name, which no directive can have, and so
+// fails to decode; this option is for data that uses "$" as a key name.
+func WithEmptyDirectiveAsLiteral() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.EmptyAsLiteral = true
+		return nil
+	}
+}
+
+// nameSet returns the set of names, or nil if names is nil.
+func nameSet(names []string) map[string]bool {
+	if names == nil {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return set
+}
+
+// setNames returns the members of set, or nil if set is nil.
+func setNames(set map[string]bool) []string {
+	if set == nil {
+		return nil
+	}
+	return slices.AppendSeq(make([]string, 0, len(set)), maps.Keys(set))
+}
+
+// WithSentinelScan makes decoding into any look for "$"-prefixed keys at every
+// position in an object, not just the first, for producers that do not
+// control key order. Without it, {"a": 1, "$std.time": "..."} decodes as a
+// plain Document.
+//
+// Members preceding or following the sentinel key are fully consumed from the
+// input and then discarded, exactly as extra fields after a leading sentinel
+// are; they do not appear in the result (or in UnmarshalWithPositions
+// output).
+//
+// If an object has several "$" keys, the one whose name (as written after the
+// "$") appears earliest in priority is dispatched; if none of them appear in
+// priority, decoding fails.
+func WithSentinelScan(priority ...string) RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.SentinelScan = true
+		o.SentinelPriority = append(o.SentinelPriority, priority...)
+		return nil
+	}
+}
+
+// WithEmptyAsNull makes the Registry's marshalers encode empty Document and
+// Array values as null, the same as nil ones. By default only nil values
+// encode as null, while empty values encode as {} and [].
+func WithEmptyAsNull() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.EmptyAsNull = true
+		return nil
+	}
+}
+
+// WithDirectiveEncoding makes the Registry's marshalers encode a value whose
+// type a registered round-trip directive (see NewRoundTripDirective)
+// produces as that directive's sentinel object, so that decoding the output
+// restores it. With the standard directives, for example, a time.Time
+// encodes as {"$std.time": "2023-10-05T14:00:00Z"} rather than as a plain
+// string. This applies wherever the type appears, struct fields included. If
+// several round-trip directives produce a type, the earliest registered is
+// used. By default values are encoded as the json package encodes them.
+func WithDirectiveEncoding() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.DirectiveEncoding = true
+		return nil
+	}
+}
+
+// WithSecretsRevealed makes the Registry's marshalers encode Secret values
+// as their plaintext string, e.g. to write resolved configuration back out for
+// a trusted consumer. By default a Secret encodes as the sentinel it was
+// decoded from, which holds its reference but not its value.
+func WithSecretsRevealed() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.RevealSecrets = true
+		return nil
+	}
+}
+
+// WithRejectUnorderedMaps makes decoding a JSON object into a map[string]any target
+// fail, instead of silently losing the object's key order.
+//
+// Objects decoded into any (including map values and interface-typed fields)
+// are already Documents; only a location whose static type is
+// map[string]any cannot hold one. Rather than let order preservation depend on
+// a field being declared any rather than map[string]any, this option reports
+// such fields so they can be changed to Document.
+func WithRejectUnorderedMaps() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.RejectUnordered = true
+		return nil
+	}
+}
+
+// WithInitialCapacity sets the capacity that every decoded non-empty Document
+// and Array starts with, avoiding early reallocations when documents are known
+// to be large. Containers still grow past the hint as needed; an overly large
+// hint wastes memory on small containers. Zero keeps the default for that kind
+// of container; negative values are an error.
+func WithInitialCapacity(objCap, arrCap int) RegistryOption {
+	return func(o *RegistryOptions) error {
+		if objCap < 0 || arrCap < 0 {
+			return fmt.Errorf("initial capacity must not be negative, got %d and %d", objCap, arrCap)
+		}
+		o.ObjectCapacity = objCap
+		o.ArrayCapacity = arrCap
+		return nil
+	}
+}
+
+// WithValueTransform makes decoding pass every primitive value (string,
+// number, boolean or null) within a decoded object or array through fn, and
+// use its result instead, e.g. to trim strings or expand "${VAR}" templates
+// without walking the tree afterwards. Directive sentinels, and the values
+// they decode, are not transformed.
+//
+// An error from fn aborts decoding with a *PathError locating the value.
+func WithValueTransform(fn func(v any) (any, error)) RegistryOption {
+	return func(o *RegistryOptions) error {
+		if fn == nil {
+			return errors.New("value transform must not be nil")
+		}
+		o.ValueTransform = fn
+		return nil
+	}
+}
+
+// WithPlainArrays makes JSON arrays decoded into any (at the root or nested)
+// have the dynamic type []any rather than Array, for code that type-asserts on
+// []any. Objects still decode as Document, and *Array targets are unaffected.
+//
+// Helpers that look for Array values, such as Document.WalkMutate, Diff and
+// ResolveRefs, treat such []any values as opaque leaves.
+func WithPlainArrays() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.PlainArrays = true
 		return nil
 	}
 }
@@ -41,40 +472,179 @@ func WithDirective(d *Directive) RegistryOption {
 // RegistryOptions accumulates directives and other configuration during
 // NewRegistry construction.
 type RegistryOptions struct {
-	Directives []*Directive
+	Directives        []*Directive
+	StringInterning   bool
+	RequireObjectRoot bool
+	MaxTotalValues    int
+	MaxStringLen      int
+	Scratch           bool
+	NoDirectives      bool
+	AllowedDirectives []string
+	DeniedDirectives  []string
+	DisallowedAsData  bool
+	EmptyAsLiteral    bool
+	SentinelScan      bool
+	SentinelPriority  []string
+	EmptyAsNull       bool
+	DirectiveEncoding bool
+	RevealSecrets     bool
+	RejectUnordered   bool
+	ObjectCapacity    int
+	ArrayCapacity     int
+	ValueTransform    func(any) (any, error)
+	PlainArrays       bool
+	DirectiveHook     func(name string, result any, err error)
+	NestedAsData      bool
+	BOMStripping      bool
+	ErrorCollection   bool
 }
 
 // NewRegistry constructs a Registry and applies any provided options (e.g.
 // directive registrations, bundles).
 //
 // It returns the initialized Registry, or an error if any registration fails.
-func NewRegistry(opts ...RegistryOption) (*Registry, error) {
+func NewRegistry(opts ...Option) (*Registry, error) {
 	cfg := &RegistryOptions{}
 	for _, opt := range opts {
 		if opt == nil {
 			continue
 		}
-		if err := opt(cfg); err != nil {
+		if err := opt.applyRegistry(cfg); err != nil {
 			return nil, err
 		}
 	}
 
 	reg := newRegistry()
-	for _, d := range cfg.Directives {
-		if err := reg.Register(d); err != nil {
+	reg.configure(cfg)
+	if err := reg.RegisterAll(cfg.Directives...); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// With returns a new Registry derived from base: it has base's directives and
+// configuration, with opts applied on top. base itself is left untouched, so a
+// shared base registry can be extended per module without side effects.
+//
+// Options that set a flag or limit override base's setting; directives are
+// registered in addition to base's. If any option or registration fails,
+// With returns nil and the error.
+func With(base *Registry, opts ...Option) (*Registry, error) {
+	cfg := &RegistryOptions{
+		StringInterning:   base.internKeys,
+		RequireObjectRoot: base.requireObjectRoot,
+		MaxTotalValues:    base.maxTotalValues,
+		MaxStringLen:      base.maxStringLen,
+		Scratch:           base.scratch,
+		NoDirectives:      base.directivesDisabled,
+		AllowedDirectives: setNames(base.allowed),
+		DeniedDirectives:  setNames(base.denied),
+		DisallowedAsData:  base.disallowedAsData,
+		EmptyAsLiteral:    base.emptyAsLiteral,
+		DirectiveHook:     base.directiveHook,
+		NestedAsData:      base.nestedAsData,
+		SentinelScan:      base.sentinelScan,
+		SentinelPriority:  slices.Clone(base.sentinelPriority),
+		EmptyAsNull:       base.emptyAsNull,
+		DirectiveEncoding: base.directiveEncoding,
+		RevealSecrets:     base.revealSecrets,
+		RejectUnordered:   base.rejectUnordered,
+		ObjectCapacity:    base.objCap,
+		ArrayCapacity:     base.arrCap,
+		ValueTransform:    base.valueTransform,
+		PlainArrays:       base.plainArrays,
+		BOMStripping:      base.bomStripping,
+		ErrorCollection:   base.collectErrors,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt.applyRegistry(cfg); err != nil {
 			return nil, err
 		}
 	}
+
+	reg := newRegistry()
+	reg.sepByte = base.sepByte
+	reg.configure(cfg)
+	// snapshots are immutable, so the derived registry can start from base's
+	reg.snap.Store(base.snap.Load())
+	if err := reg.RegisterAll(cfg.Directives...); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// RegistryFromFuncs constructs a Registry with one directive per entry of m,
+// keyed by directive name, for hosts whose directive set is only known at run
+// time (e.g. loaded from plugins or configuration). Each directive produces
+// an untyped value, so none is used for DirectiveForType.
+//
+// Names are validated as by Register, in sorted order. If any is invalid or m
+// holds a nil func, RegistryFromFuncs returns nil and the error.
+func RegistryFromFuncs(m map[string]func(*jsontext.Decoder) (any, error)) (*Registry, error) {
+	ds := make([]*Directive, 0, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		fn := m[name]
+		if fn == nil {
+			return nil, fmt.Errorf("directive %q has a nil func", name)
+		}
+		ds = append(ds, NewRawDirective(name, fn))
+	}
+	reg, err := NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	if err := reg.RegisterAll(ds...); err != nil {
+		return nil, err
+	}
 	return reg, nil
 }
 
+// configure applies the non-directive settings collected in cfg.
+func (r *Registry) configure(cfg *RegistryOptions) {
+	r.internKeys = cfg.StringInterning
+	r.requireObjectRoot = cfg.RequireObjectRoot
+	r.maxTotalValues = cfg.MaxTotalValues
+	r.maxStringLen = cfg.MaxStringLen
+	r.scratch = cfg.Scratch
+	r.directivesDisabled = cfg.NoDirectives
+	r.allowed = nameSet(cfg.AllowedDirectives)
+	r.denied = nameSet(cfg.DeniedDirectives)
+	r.disallowedAsData = cfg.DisallowedAsData
+	r.emptyAsLiteral = cfg.EmptyAsLiteral
+	r.directiveHook = cfg.DirectiveHook
+	r.nestedAsData = cfg.NestedAsData
+	if r.nestedAsData {
+		r.plainOpts = newPlainRegistry().unmarshalOpts
+	}
+	r.sentinelScan = cfg.SentinelScan
+	r.sentinelPriority = cfg.SentinelPriority
+	r.emptyAsNull = cfg.EmptyAsNull
+	r.directiveEncoding = cfg.DirectiveEncoding
+	r.revealSecrets = cfg.RevealSecrets
+	r.rejectUnordered = cfg.RejectUnordered
+	r.objCap = cfg.ObjectCapacity
+	r.arrCap = cfg.ArrayCapacity
+	r.valueTransform = cfg.ValueTransform
+	r.plainArrays = cfg.PlainArrays
+	r.bomStripping = cfg.BOMStripping
+	r.collectErrors = cfg.ErrorCollection
+}
+
 // newRegistry constructs an empty Registry with default settings.
 func newRegistry() *Registry {
-	return &Registry{
+	r := &Registry{sepByte: '.'}
+	r.shared = Unmarshalers(r)
+	r.unmarshalOpts = json.WithUnmarshalers(r.shared)
+	r.stateless = &decodeState{reg: r}
+	r.snap.Store(&registrySnapshot{
 		entries: make(map[string]*Directive),
 		shorts:  make(map[string][]string),
-		sepByte: '.',
-	}
+		types:   make(map[reflect.Type][]string),
+	})
+	return r
 }
 
 var defaultRegistry atomic.Pointer[Registry]
@@ -103,86 +673,495 @@ func (r *Registry) Register(d *Directive) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	next := r.snap.Load().clone()
+	if err := next.insert(d, r.sepByte); err != nil {
+		return err
+	}
+	r.snap.Store(next)
+	return nil
+}
+
+// RegisterAll inserts several directives into the Registry at once.
+//
+// All directives are validated and inserted under a single write lock and
+// published together. If any registration fails (including a duplicate within
+// ds itself), none of them are registered and the Registry is unchanged.
+func (r *Registry) RegisterAll(ds ...*Directive) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.snap.Load().clone()
+	for _, d := range ds {
+		if err := next.insert(d, r.sepByte); err != nil {
+			return err
+		}
+	}
+	r.snap.Store(next)
+	return nil
+}
+
+// RegisterNamespace registers each directive in ds under the name ns + sep +
+// key, where sep is the Registry's namespace separator, e.g.
+//
+//	reg.RegisterNamespace("geo", map[string]*jwalk.Directive{
+//	    "point":   pointDirective,
+//	    "polygon": polygonDirective,
+//	}) // registers "geo.point" and "geo.polygon"
+//
+// The map keys replace the directives' own names; the directives themselves
+// are not modified. The resulting names are validated as by Register, and as
+// with RegisterAll, either all of them are registered or, on error, none.
+func (r *Registry) RegisterNamespace(ns string, ds map[string]*Directive) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.snap.Load().clone()
+	for _, name := range slices.Sorted(maps.Keys(ds)) {
+		d := ds[name]
+		if d == nil {
+			return fmt.Errorf("directive %q is nil", name)
+		}
+		full := ns + string(r.sepByte) + name
+		if err := next.insert(&Directive{name: full, typ: d.typ, mode: d.mode, call: d.call, encode: d.encode, stateful: d.stateful}, r.sepByte); err != nil {
+			return err
+		}
+	}
+	r.snap.Store(next)
+	return nil
+}
+
+// Unregister removes the directive with the given fully qualified name from the
+// Registry, along with its short-name index entry. It returns an error if no
+// directive is registered under that name.
+func (r *Registry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.snap.Load().clone()
+	if err := next.remove(name, r.sepByte); err != nil {
+		return err
+	}
+	r.snap.Store(next)
+	return nil
+}
+
+// remove deletes the named directive from s.
+func (s *registrySnapshot) remove(name string, sep byte) error {
+	d, exists := s.entries[name]
+	if !exists {
+		return fmt.Errorf("directive %q not registered", name)
+	}
+	delete(s.entries, name)
+	if d.stateful {
+		s.stateful--
+	}
+
+	if d.typ != nil {
+		rest := slices.DeleteFunc(slices.Clone(s.types[d.typ]), func(n string) bool { return n == name })
+		if len(rest) == 0 {
+			delete(s.types, d.typ)
+		} else {
+			s.types[d.typ] = rest
+		}
+	}
+
+	if idx := strings.LastIndexByte(name, sep); idx >= 0 {
+		short := name[idx+1:]
+		// clone before filtering; the backing array may be shared with
+		// published snapshots
+		rest := slices.DeleteFunc(slices.Clone(s.shorts[short]), func(n string) bool { return n == name })
+		if len(rest) == 0 {
+			delete(s.shorts, short)
+		} else {
+			s.shorts[short] = rest
+		}
+	}
+	return nil
+}
+
+// insert validates d's name and adds it to s.
+func (s *registrySnapshot) insert(d *Directive, sep byte) error {
 	name := d.name
-	if _, exists := r.entries[name]; exists {
+	if _, exists := s.entries[name]; exists {
 		return fmt.Errorf("directive %q already registered", name)
 	}
 
 	// validate namespace form: either bare (no separator) or exactly one
 	// separator producing two non-empty components (ns.name).
-	idx := strings.LastIndexByte(name, r.sepByte)
+	idx := strings.LastIndexByte(name, sep)
 	if idx >= 0 { // namespaced
-		if idx == len(name)-1 || strings.IndexByte(name, r.sepByte) != idx {
+		if idx == 0 || idx == len(name)-1 || strings.IndexByte(name, sep) != idx {
 			return fmt.Errorf("directive %q invalid namespace (expected ns.name)", name)
 		}
 	}
 
-	r.entries[name] = d
+	s.entries[name] = d
+	if d.stateful {
+		s.stateful++
+	}
 	if idx >= 0 {
 		short := name[idx+1:]
-		r.shorts[short] = append(r.shorts[short], name)
+		s.shorts[short] = append(s.shorts[short], name)
+	}
+	if d.typ != nil {
+		s.types[d.typ] = append(s.types[d.typ], name)
 	}
 	return nil
 }
 
+// DirectiveForType returns the fully qualified name of the directive that
+// produces values of type t, e.g. "std.time" for time.Time. If several
+// directives produce t, the earliest registered is returned.
+//
+// Directives whose result type is an interface (such as any) are not indexed,
+// since their results have no single type.
+func (r *Registry) DirectiveForType(t reflect.Type) (string, bool) {
+	names := r.snap.Load().types[t]
+	if len(names) == 0 {
+		return "", false
+	}
+	return names[0], true
+}
+
+// Conflicts reports the short names that do not resolve to every directive
+// registered under them, so those directives must be written with their
+// fully qualified names, e.g. to check a registry assembled from plugins at
+// startup:
+//
+//	"time": ambiguous between std.time, x.time
+//	"time": bare directive shadows std.time
+//
+// The first form is a short name shared by several namespaced directives,
+// which fails to resolve bare; the second a bare directive whose name is also
+// a namespaced directive's short name, which resolves bare to the bare
+// directive. The result is sorted, and empty if there are no conflicts.
+func (r *Registry) Conflicts() []string {
+	snap := r.snap.Load()
+	var conflicts []string
+	for short, names := range snap.shorts {
+		names = slices.Sorted(slices.Values(names))
+		if _, ok := snap.entries[short]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%q: bare directive shadows %s", short, strings.Join(names, ", ")))
+		} else if len(names) > 1 {
+			conflicts = append(conflicts, fmt.Sprintf("%q: ambiguous between %s", short, strings.Join(names, ", ")))
+		}
+	}
+	slices.Sort(conflicts)
+	return conflicts
+}
+
+// Validate returns an error listing the Conflicts, if there are any.
+func (r *Registry) Validate() error {
+	conflicts := r.Conflicts()
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("directive name conflicts: %s", strings.Join(conflicts, "; "))
+}
+
 // InvokeDirective looks up and executes a directive by name.
 //
+// dec must be positioned at the sentinel's value for a ValueMode directive,
+// or before the opening brace of the whole sentinel object for an ObjectMode
+// one.
+//
 // Both fully qualified and bare names are supported. Bare lookup succeeds only
-// if unambiguous. If no directive matches, or if multiple directives share the
-// same short name, an error is returned.
+// if unambiguous. If no directive matches, if multiple directives share the
+// same short name, or if the Registry has WithDirectivesDisabled, an error is
+// returned.
 func (r *Registry) InvokeDirective(name string, dec *jsontext.Decoder) (any, error) {
-	r.mu.RLock()
-	var ambiguous bool
-	var matches []string
-	ent, ok := r.entries[name]
-	if !ok {
-		if strings.LastIndexByte(name, r.sepByte) == -1 {
-			matches = r.shorts[name]
-			switch len(matches) {
-			case 0:
-				// no match
-			case 1:
-				ent, ok = r.entries[matches[0]]
-			default:
-				ambiguous = true
-			}
+	if r.directivesDisabled {
+		return nil, fmt.Errorf("directive %q: directives are disabled", name)
+	}
+	ent, err := r.snap.Load().lookup(name, r.sepByte)
+	if err != nil {
+		return nil, err
+	}
+	defer r.ownState(dec)()
+
+	if r.nestedAsData {
+		raw, err := dec.ReadValue()
+		if err != nil {
+			return nil, fmt.Errorf("directive %q: %w", ent.name, err)
 		}
+		var release func()
+		dec, release = newSubDecoder(dec, bytes.NewReader(raw.Clone()), r.plainOpts)
+		defer release()
+	}
+
+	v, err := ent.call(dec)
+	if err != nil {
+		v, err = nil, fmt.Errorf("directive %q: %w", ent.name, err)
 	}
-	r.mu.RUnlock()
+	if r.directiveHook != nil {
+		r.directiveHook(ent.name, v, err)
+	}
+	return v, err
+}
 
-	if !ok {
-		if ambiguous {
+// dispatchesDirectives reports whether decoding may invoke a directive: some
+// are registered, and WithDirectivesDisabled is not set.
+func (r *Registry) dispatchesDirectives() bool {
+	return !r.directivesDisabled && len(r.snap.Load().entries) > 0
+}
+
+// lookup resolves a fully qualified or unambiguous bare name to its directive.
+func (s *registrySnapshot) lookup(name string, sep byte) (*Directive, error) {
+	if ent, ok := s.entries[name]; ok {
+		return ent, nil
+	}
+	if strings.LastIndexByte(name, sep) == -1 {
+		matches := s.shorts[name]
+		switch len(matches) {
+		case 0:
+			// no match
+		case 1:
+			return s.entries[matches[0]], nil
+		default:
 			return nil, fmt.Errorf("directive %q ambiguous (%s)", name, strings.Join(matches, ", "))
 		}
-		return nil, fmt.Errorf("directive %q not registered", name)
 	}
+	return nil, fmt.Errorf("directive %q not registered", name)
+}
 
-	v, err := ent.call(dec)
+// Alias registers newName as a synonym for the directive currently resolved by
+// existingName (a fully qualified or unambiguous bare name), e.g.
+//
+//	reg.Alias("when", "std.time") // {"$when": ...} behaves like {"$std.time": ...}
+//
+// newName is subject to the same namespace validation and duplicate checks as
+// Register. The alias shares the original's decode function but is otherwise
+// independent: unregistering the original does not remove or break the alias.
+func (r *Registry) Alias(newName, existingName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur := r.snap.Load()
+	orig, err := cur.lookup(existingName, r.sepByte)
 	if err != nil {
-		return nil, fmt.Errorf("directive %q: %w", ent.name, err)
+		return err
 	}
 
-	return v, nil
+	next := cur.clone()
+	if err := next.insert(&Directive{name: newName, typ: orig.typ, mode: orig.mode, call: orig.call, encode: orig.encode, stateful: orig.stateful}, r.sepByte); err != nil {
+		return err
+	}
+	r.snap.Store(next)
+	return nil
+}
+
+// Options returns the json options that install the Registry’s unmarshalers,
+// for callers that manage their own option lists, e.g.
+//
+//	opts := append(reg.Options(), json.WithMarshalers(myMarshalers))
+//	err := json.Unmarshal(in, &v, opts...)
+//
+// The options are built once per Registry and may be reused across any number
+// of calls; they reflect directives registered later. The returned slice is
+// the caller's to modify.
+func (r *Registry) Options() []json.Options {
+	return []json.Options{r.unmarshalOpts}
 }
 
 // Unmarshal decodes JSON input using the Registry’s unmarshalers.
 //
 // This is a convenience wrapper over json.Unmarshal that ensures jwalk-specific
-// object/array/directive handling is available.
+// object/array/directive handling is available. A failing directive is
+// reported as a *PathError locating it in the tree.
 func (r *Registry) Unmarshal(in []byte, out any, opts ...json.Options) error {
-	return json.Unmarshal(in, out, append([]json.Options{json.WithUnmarshalers(Unmarshalers(r))}, opts...)...)
+	if r.stateful() || r.collectErrors {
+		return r.decodeSeeded(in, out, r.newState(), opts...)
+	}
+	return nestedError(json.Unmarshal(in, out, append(r.Options(), opts...)...))
+}
+
+// UnmarshalAppend decodes a JSON object from in and appends its entries to
+// *d, keeping any entries already present, e.g. to build one Document from
+// several sources in sequence:
+//
+//	var merged jwalk.Document
+//	for _, src := range sources {
+//	    if err := reg.UnmarshalAppend(src, &merged); err != nil { ... }
+//	}
+//
+// Keys are not deduplicated; use ApplyMergePatch to merge by key instead. If
+// decoding fails, *d is left unchanged. Unmarshal into *Document, by contrast,
+// always replaces the previous content.
+func (r *Registry) UnmarshalAppend(in []byte, d *Document, opts ...json.Options) error {
+	var next Document
+	if err := r.Unmarshal(in, &next, opts...); err != nil {
+		return err
+	}
+	if next == nil {
+		return errors.New("cannot append null to a Document")
+	}
+	*d = append(*d, next...)
+	return nil
+}
+
+// Decode decodes a single JSON value read from rd using the Registry’s
+// unmarshalers. Input is consumed through a jsontext.Decoder as decoding
+// proceeds rather than read into memory up front, making this the streaming
+// counterpart of Unmarshal.
+//
+// As with Unmarshal, anything other than whitespace after the value is an
+// error, so rd is read to EOF. Under WithBOMStripping, the input may start
+// with a byte order mark.
+func (r *Registry) Decode(rd io.Reader, out any, opts ...json.Options) error {
+	rd, err := r.inputReader(rd)
+	if err != nil {
+		return err
+	}
+	if r.stateful() || r.collectErrors {
+		return r.decodeFrom(rd, out, r.newState(), opts...)
+	}
+	return decodeAll(jsontext.NewDecoder(rd, append(r.Options(), opts...)...), out)
+}
+
+// UnmarshalAllowing decodes in like Unmarshal, but may dispatch only the
+// directives named in allowed (fully qualified names), on top of the
+// Registry's own allow and deny lists. A sentinel naming any other directive
+// fails to decode, or is decoded as data if the Registry has
+// WithDisallowedAsData, e.g.
+//
+//	err := reg.UnmarshalAllowing(in, &doc, []string{"std.time"})
+//
+// lets one registry with $include or $env registered decode less-trusted
+// input without running them.
+func (r *Registry) UnmarshalAllowing(in []byte, out any, allowed []string, opts ...json.Options) error {
+	st := r.newState()
+	st.allowed = nameSet(append([]string{}, allowed...))
+	return r.decodeSeeded(in, out, st, opts...)
+}
+
+// DecodeValue decodes JSON input into a dynamically typed value, always
+// dispatching a directive sentinel at the root.
+//
+// Unmarshal into *Document deliberately treats a root {"$name": ...} object as
+// plain data. DecodeValue instead returns the directive result (e.g. a
+// time.Time for {"$std.time": ...}), or a Document, Array or primitive for
+// non-directive input.
+func (r *Registry) DecodeValue(in []byte, opts ...json.Options) (any, error) {
+	var v any
+	if err := r.Unmarshal(in, &v, opts...); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Marshal encodes v using the Registry’s marshalers.
+//
+// This is a convenience wrapper over json.Marshal that ensures Document and
+// Array values are emitted in order.
+func (r *Registry) Marshal(v any, opts ...json.Options) ([]byte, error) {
+	return json.Marshal(v, append([]json.Options{json.WithMarshalers(Marshalers(r))}, opts...)...)
+}
+
+// RoundTrip decodes data with reg and re-encodes the result, returning the
+// encoded form. Comparing it against data (or a golden file) checks that a
+// directive set preserves order and structure, e.g.
+//
+//	out, err := jwalk.RoundTrip(in, reg)
+//
+// Objects and arrays keep their order. Results of round-trip directives (see
+// NewRoundTripDirective) are encoded as their sentinel objects, as under
+// WithDirectiveEncoding, so the output decodes to the same values; results of
+// other directives are encoded as the values they decode to. Members a
+// sentinel ignores are dropped and the output is compact, so data containing
+// sentinels or whitespace need not round-trip byte for byte, but the output
+// round-trips to itself.
+func RoundTrip(data []byte, reg *Registry) ([]byte, error) {
+	v, err := reg.DecodeValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v, json.WithMarshalers(marshalers(reg, true)))
+}
+
+// EncodeWriter encodes v as ordered JSON to w using the Registry’s marshalers.
+//
+// It is the saving half of a load, modify, save cycle, so unlike Marshal it
+// always encodes the results of round-trip directives (see
+// NewRoundTripDirective) as their sentinel objects, as under
+// WithDirectiveEncoding, and the output decodes to the same values: a
+// time.Time loaded from {"$std.time": "..."} is saved as one.
+//
+// Pass jsontext.WithIndent (and optionally jsontext.WithIndentPrefix) to
+// produce human-readable output.
+func (r *Registry) EncodeWriter(w io.Writer, v any, opts ...json.Options) error {
+	return json.MarshalWrite(w, v, append([]json.Options{json.WithMarshalers(marshalers(r, true))}, opts...)...)
+}
+
+// EncodeFile encodes v as ordered JSON and writes it to the named file,
+// creating or truncating it. A trailing newline is appended so the output is
+// suitable for configuration files. Like EncodeWriter, it encodes the results
+// of round-trip directives as their sentinel objects.
+//
+// Pass jsontext.WithIndent to produce human-readable output, e.g.
+//
+//	err := reg.EncodeFile("config.json", doc, jsontext.WithIndent("  "))
+func (r *Registry) EncodeFile(path string, v any, opts ...json.Options) error {
+	b, err := json.Marshal(v, append([]json.Options{json.WithMarshalers(marshalers(r, true))}, opts...)...)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
 }
 
 // Directive describes a directive handler bound to a specific name.
+//
+// A directive's decode function reads its value from a decoder that carries
+// the Registry's unmarshalers, so a sentinel nested in that value, as in
+//
+//	{"$outer": {"$inner": 1}}
+//
+// is dispatched exactly when the function decodes the part holding it into a
+// type that jwalk handles, such as any, Document or Array: it then sees the
+// $inner directive's result. Reading that part any other way, e.g. with
+// ReadValue or into a struct or jsontext.Value, sees the inner sentinel as
+// plain JSON. A directive thus opts in to nested dispatch by how it decodes.
+// WithNestedSentinelsAsData turns nested dispatch off for all directives.
 type Directive struct {
 	name string
+	typ  reflect.Type // result type; nil if the result is dynamically typed
+	mode DirectiveMode
 	call func(dec *jsontext.Decoder) (any, error)
+
+	encode func(enc *jsontext.Encoder, v any) error // inverse of call, if any (NewRoundTripDirective)
+
+	stateful bool // call uses per-decode state (NewIncludeDirective)
+}
+
+// DirectiveMode selects which part of a sentinel object a directive decodes.
+type DirectiveMode int
+
+const (
+	// ValueMode directives decode the value of the "$name" member; any other
+	// members of the sentinel object are skipped. See NewDirective.
+	ValueMode DirectiveMode = iota
+
+	// ObjectMode directives decode the whole sentinel object, "$name" member
+	// included, so they can use its sibling members. See NewObjectDirective.
+	ObjectMode
+)
+
+// Mode reports which part of a sentinel object d decodes.
+func (d *Directive) Mode() DirectiveMode {
+	return d.mode
 }
 
 type Unmarshaler[T any] func(dec *jsontext.Decoder) (T, error)
 
+// Validator checks a decoded directive value. It is run after the directive's
+// Unmarshaler succeeds; a non-nil error fails the directive.
+type Validator[T any] func(T) error
+
 // NewDirective constructs a Directive given a name and a typed decode function.
 //
+// Optional validators are run in order on the decoded value, separating
+// parsing from validation. A failing validator is reported as
+// `directive "<name>": invalid value: <err>`.
+//
 // Example:
 //
 //	d := jwalk.NewDirective("std.time", func(dec *jsontext.Decoder) (time.Time, error) {
@@ -192,9 +1171,86 @@ type Unmarshaler[T any] func(dec *jsontext.Decoder) (T, error)
 //	    }
 //	    return time.Parse(time.RFC3339, s)
 //	})
-func NewDirective[T any](name string, unmarshaler Unmarshaler[T]) *Directive {
+func NewDirective[T any](name string, unmarshaler Unmarshaler[T], validators ...Validator[T]) *Directive {
 	wrapper := func(dec *jsontext.Decoder) (any, error) {
-		return unmarshaler(dec)
+		v, err := unmarshaler(dec)
+		if err != nil {
+			return nil, err
+		}
+		for _, validate := range validators {
+			if err := validate(v); err != nil {
+				return nil, fmt.Errorf("invalid value: %w", err)
+			}
+		}
+		return v, nil
+	}
+	return &Directive{name: name, typ: resultType[T](), call: wrapper}
+}
+
+// NewObjectDirective constructs an ObjectMode Directive given a name and a
+// typed decode function. Unlike NewDirective, the decode function is handed
+// the decoder positioned before the sentinel object's opening brace and must
+// consume the whole object, e.g. for
+//
+//	{"$money": 12.5, "currency": "EUR"}
+//
+// it may decode into a struct with fields for both "$money" and "currency".
+// Validators behave as for NewDirective.
+func NewObjectDirective[T any](name string, unmarshaler Unmarshaler[T], validators ...Validator[T]) *Directive {
+	d := NewDirective(name, unmarshaler, validators...)
+	d.mode = ObjectMode
+	return d
+}
+
+// Marshaler encodes a value of type T as a directive's input: the value of
+// the "$name" member for a ValueMode directive, or the whole sentinel object
+// for an ObjectMode one.
+type Marshaler[T any] func(enc *jsontext.Encoder, v T) error
+
+// NewRoundTripDirective constructs a Directive like NewDirective, with a
+// marshaler, the inverse of unmarshaler, through which WithDirectiveEncoding
+// encodes values of type T as the directive's sentinel objects, e.g.
+//
+//	d := jwalk.NewRoundTripDirective("duration", unmarshalDuration,
+//	    func(enc *jsontext.Encoder, d time.Duration) error {
+//	        return enc.WriteToken(jsontext.String(d.String()))
+//	    })
+//
+// encodes a time.Duration as {"$duration": "1h30m0s"}. The marshaler's
+// encoder does not itself encode values as directives, so the marshaler may
+// encode v with json.MarshalEncode. T must not be an interface type.
+func NewRoundTripDirective[T any](name string, unmarshaler Unmarshaler[T], marshaler Marshaler[T], validators ...Validator[T]) *Directive {
+	d := NewDirective(name, unmarshaler, validators...)
+	d.encode = func(enc *jsontext.Encoder, v any) error { return marshaler(enc, v.(T)) }
+	return d
+}
+
+// encoderFor returns the directive that WithDirectiveEncoding encodes values
+// of type t through, or nil if there is none.
+func (r *Registry) encoderFor(t reflect.Type) *Directive {
+	snap := r.snap.Load()
+	for _, name := range snap.types[t] {
+		if d := snap.entries[name]; d.encode != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+// NewRawDirective constructs a Directive from an untyped decode function, for
+// directives whose result type is inherently dynamic, such as $include or a
+// fallback that returns whatever it decodes. It is NewDirective with T = any,
+// spelled out; like any directive producing an interface type, it is not
+// indexed for DirectiveForType.
+func NewRawDirective(name string, fn func(dec *jsontext.Decoder) (any, error)) *Directive {
+	return NewDirective(name, fn)
+}
+
+// resultType returns the reflect.Type of T, or nil if T is an interface type.
+func resultType[T any]() reflect.Type {
+	t := reflect.TypeFor[T]()
+	if t.Kind() == reflect.Interface {
+		return nil
 	}
-	return &Directive{name: name, call: wrapper}
+	return t
 }
@@ -0,0 +1,362 @@
+package jwalk_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestNewRegistryOptions(t *testing.T) {
+	var budget jwalk.RegistryOption = func(o *jwalk.RegistryOptions) error {
+		o.MaxTotalValues = 3
+		return nil
+	}
+	opts := []jwalk.Option{
+		budget,
+		jwalk.RegistryOption(func(o *jwalk.RegistryOptions) error { return nil }),
+		jwalk.RegistryOption(nil),
+		jwalk.StdTimeDirective,
+		jwalk.WithDirective(jwalk.StdDurationDirective),
+	}
+	reg, err := jwalk.NewRegistry(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v any
+	if err := reg.Unmarshal([]byte(`{"a": {"$std.duration": "1s"}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.(jwalk.Document)[0].Value; got != time.Second {
+		t.Errorf("a = %v, want 1s", got)
+	}
+	if err := reg.Unmarshal([]byte(`[1, 2, 3]`), &v); !errors.Is(err, jwalk.ErrMaxTotalValues) {
+		t.Errorf("Unmarshal error = %v, want ErrMaxTotalValues", err)
+	}
+
+	failing := jwalk.RegistryOption(func(o *jwalk.RegistryOptions) error { return errors.New("bad option") })
+	if _, err := jwalk.NewRegistry(jwalk.StdTimeDirective, failing); err == nil || err.Error() != "bad option" {
+		t.Errorf("NewRegistry error = %v, want bad option", err)
+	}
+}
+
+// TestRegistryConcurrency registers, unregisters and invokes directives from
+// many goroutines at once. It is meant to be run with -race.
+func TestRegistryConcurrency(t *testing.T) {
+	constant := func(name string, v int) *jwalk.Directive {
+		return jwalk.NewDirective(name, func(dec *jsontext.Decoder) (int, error) {
+			return v, dec.SkipValue()
+		})
+	}
+	reg, err := jwalk.NewRegistry(constant("a.stable", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, rounds = 8, 200
+	var wg sync.WaitGroup
+	for w := range workers {
+		// writers churn directives whose short name "churn" is shared
+		// across namespaces, so the short-name index changes constantly
+		wg.Go(func() {
+			name := fmt.Sprintf("n%d.churn", w)
+			for range rounds {
+				if err := reg.Register(constant(name, w)); err != nil {
+					t.Errorf("Register(%s): %v", name, err)
+					return
+				}
+				if err := reg.Alias(fmt.Sprintf("alias%d", w), name); err != nil {
+					t.Errorf("Alias(%s): %v", name, err)
+					return
+				}
+				_ = reg.Conflicts()
+				if err := reg.Unregister(fmt.Sprintf("alias%d", w)); err != nil {
+					t.Errorf("Unregister(alias%d): %v", w, err)
+					return
+				}
+				if err := reg.Unregister(name); err != nil {
+					t.Errorf("Unregister(%s): %v", name, err)
+					return
+				}
+			}
+		})
+
+		// readers see either a directive or a clean lookup error, never a
+		// directive registered under another name
+		wg.Go(func() {
+			for i := range rounds {
+				for _, name := range []string{"a.stable", "stable"} {
+					v, err := reg.InvokeDirective(name, jsontext.NewDecoder(strings.NewReader("null")))
+					if err != nil || v != 1 {
+						t.Errorf("InvokeDirective(%s) = %v, %v; want 1", name, v, err)
+						return
+					}
+				}
+				name := fmt.Sprintf("n%d.churn", i%workers)
+				v, err := reg.InvokeDirective(name, jsontext.NewDecoder(strings.NewReader("null")))
+				if err == nil && v != i%workers {
+					t.Errorf("InvokeDirective(%s) = %v, want %d", name, v, i%workers)
+					return
+				}
+				if err != nil && !strings.Contains(err.Error(), "not registered") {
+					t.Errorf("InvokeDirective(%s) error = %v", name, err)
+					return
+				}
+				_, err = reg.InvokeDirective("churn", jsontext.NewDecoder(strings.NewReader("null")))
+				if err != nil && !strings.Contains(err.Error(), "not registered") && !strings.Contains(err.Error(), "ambiguous") {
+					t.Errorf("InvokeDirective(churn) error = %v", err)
+					return
+				}
+
+				var doc any
+				if err := reg.Unmarshal([]byte(`{"x": {"$stable": null}, "y": [{"$a.stable": 0}]}`), &doc); err != nil {
+					t.Errorf("Unmarshal: %v", err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := reg.Conflicts(); len(got) != 0 {
+		t.Errorf("Conflicts after churn = %v, want none", got)
+	}
+}
+
+// BenchmarkInvokeDirectiveParallel looks up and invokes a directive from 16
+// goroutines at once. The RWMutex case takes a shared read lock around each
+// call, as lookups did before the Registry kept its directives in an atomic
+// snapshot, for comparison with the lock-free lookup.
+func BenchmarkInvokeDirectiveParallel(b *testing.B) {
+	reg, err := jwalk.NewRegistry(jwalk.WithStdlib())
+	if err != nil {
+		b.Fatal(err)
+	}
+	var mu sync.RWMutex
+	for _, bm := range []struct {
+		name   string
+		invoke func(dec *jsontext.Decoder) (any, error)
+	}{
+		{"Snapshot", func(dec *jsontext.Decoder) (any, error) {
+			return reg.InvokeDirective("duration", dec)
+		}},
+		{"RWMutex", func(dec *jsontext.Decoder) (any, error) {
+			mu.RLock()
+			defer mu.RUnlock()
+			return reg.InvokeDirective("duration", dec)
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			const goroutines = 16
+			b.ReportAllocs()
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for g := range goroutines {
+				wg.Go(func() {
+					in := strings.NewReader(`"1s"`)
+					dec := jsontext.NewDecoder(in)
+					for i := g; i < b.N; i += goroutines {
+						in.Reset(`"1s"`)
+						dec.Reset(in)
+						if _, err := bm.invoke(dec); err != nil {
+							b.Error(err)
+							return
+						}
+					}
+				})
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// exampleInput is the input of examples/main.go.
+const exampleInput = `{
+	"name": "example",
+	"created": {"$std.time": "2023-10-01T12:00:00Z"},
+	"timeout": {"$std.duration": "5m30s"},
+	"config": {
+		"enabled": true,
+		"retry_after": {"$std.duration": "1h"}
+	},
+	"events": [
+		{"$std.time": "2023-10-01T12:05:00Z", "other": "data"},
+		{"$std.time": "2023-10-01T12:10:00Z"}
+	]
+}`
+
+func TestRoundTrip(t *testing.T) {
+	const (
+		// sentinels are plain objects to a registry without directives
+		asData = `{"name":"example","created":{"$std.time":"2023-10-01T12:00:00Z"},"timeout":{"$std.duration":"5m30s"},` +
+			`"config":{"enabled":true,"retry_after":{"$std.duration":"1h"}},` +
+			`"events":[{"$std.time":"2023-10-01T12:05:00Z","other":"data"},{"$std.time":"2023-10-01T12:10:00Z"}]}`
+		// directive results encode as sentinels again, less the members a
+		// sentinel ignores
+		asSentinels = `{"name":"example","created":{"$std.time":"2023-10-01T12:00:00Z"},"timeout":{"$std.duration":"5m30s"},` +
+			`"config":{"enabled":true,"retry_after":{"$std.duration":"1h0m0s"}},` +
+			`"events":[{"$std.time":"2023-10-01T12:05:00Z"},{"$std.time":"2023-10-01T12:10:00Z"}]}`
+		plain = `{"z":1,"a":[3,2,1,{"y":null,"b":false}],"m":{"k2":"v","k1":-0.5e-3},"e":{},"l":[]}`
+	)
+	for _, tt := range []struct {
+		name string
+		opts []jwalk.Option
+		in   string
+		want string
+	}{
+		{"directives disabled/example", []jwalk.Option{jwalk.WithStdlib(), jwalk.WithDirectivesDisabled()}, exampleInput, asData},
+		{"stdlib/example", []jwalk.Option{jwalk.WithStdlib()}, exampleInput, asSentinels},
+		{"directive encoding/example", []jwalk.Option{jwalk.WithStdlib(), jwalk.WithDirectiveEncoding()}, exampleInput, asSentinels},
+		{"example directives/example", []jwalk.Option{jwalk.StdTimeDirective, jwalk.StdDurationDirective}, exampleInput, asSentinels},
+		{"other directives", []jwalk.Option{anyDirective}, `{"w": {"$wrap": [1, {"b": 2, "a": 1}]}}`, `{"w":[1,{"b":2,"a":1}]}`},
+		{"no directives/plain", nil, plain, `{"z":1,"a":[3,2,1,{"y":null,"b":false}],"m":{"k2":"v","k1":-0.0005},"e":{},"l":[]}`},
+		{"stdlib/plain", []jwalk.Option{jwalk.WithStdlib()}, plain, `{"z":1,"a":[3,2,1,{"y":null,"b":false}],"m":{"k2":"v","k1":-0.0005},"e":{},"l":[]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := jwalk.RoundTrip([]byte(tt.in), reg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.want {
+				t.Fatalf("RoundTrip = %s\nwant        %s", out, tt.want)
+			}
+			// the output is stable: it round-trips to itself
+			again, err := jwalk.RoundTrip(out, reg)
+			if err != nil || string(again) != string(out) {
+				t.Errorf("RoundTrip(RoundTrip(in)) = %s (%v), want %s", again, err, out)
+			}
+		})
+	}
+}
+
+// rawDirective returns its value's JSON text without decoding it.
+var rawDirective = jwalk.NewDirective("raw", func(dec *jsontext.Decoder) (string, error) {
+	v, err := dec.ReadValue()
+	return string(v), err
+})
+
+func TestNestedSentinels(t *testing.T) {
+	const in = `{"w": {"$wrap": {"$std.duration": "1s"}}, "a": {"$wrap": [{"$std.duration": "2s"}]}, "r": {"$raw": {"$std.duration": "3s"}}}`
+	for _, tt := range []struct {
+		name string
+		opts []jwalk.Option
+		w, a any // what $wrap hands back
+	}{
+		// a directive decoding its value into any has nested sentinels
+		// dispatched; one reading the value itself sees them raw
+		{"default", nil, time.Second, jwalk.Array{2 * time.Second}},
+		{"SentinelScan", []jwalk.Option{jwalk.WithSentinelScan()}, time.Second, jwalk.Array{2 * time.Second}},
+		{"NestedSentinelsAsData", []jwalk.Option{jwalk.WithNestedSentinelsAsData()},
+			jwalk.Document{{Key: "$std.duration", Value: "1s"}},
+			jwalk.Array{jwalk.Document{{Key: "$std.duration", Value: "2s"}}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(append(tt.opts, jwalk.WithStdlib(), anyDirective, rawDirective)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, decode := range []struct {
+				name string
+				fn   func(*any) error
+			}{
+				{"Registry.Unmarshal", func(v *any) error { return reg.Unmarshal([]byte(in), v) }},
+				{"json.Unmarshal", func(v *any) error { return json.Unmarshal([]byte(in), v, reg.Options()...) }},
+			} {
+				var v any
+				if err := decode.fn(&v); err != nil {
+					t.Fatalf("%s: %v", decode.name, err)
+				}
+				d := v.(jwalk.Document)
+				if !reflect.DeepEqual(d[0].Value, tt.w) {
+					t.Errorf("%s: w = %#v, want %#v", decode.name, d[0].Value, tt.w)
+				}
+				if !reflect.DeepEqual(d[1].Value, tt.a) {
+					t.Errorf("%s: a = %#v, want %#v", decode.name, d[1].Value, tt.a)
+				}
+				if want := `{"$std.duration": "3s"}`; d[2].Value != want {
+					t.Errorf("%s: r = %#v, want %#v", decode.name, d[2].Value, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeFile(t *testing.T) {
+	const want = `{"name":"example","created":{"$std.time":"2023-10-01T12:00:00Z"},"timeout":{"$std.duration":"5m30s"},` +
+		`"config":{"enabled":true,"retry_after":{"$std.duration":"1h0m0s"}},` +
+		`"events":[{"$std.time":"2023-10-01T12:05:00Z"},{"$std.time":"2023-10-01T12:10:00Z"}],"retries":3}`
+	reg, err := jwalk.NewRegistry(jwalk.WithStdlib())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// load, modify
+	var doc jwalk.Document
+	if err := reg.Unmarshal([]byte(exampleInput), &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc = append(doc, jwalk.Entry{Key: "retries", Value: 3.0})
+
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name    string
+		save    func() ([]byte, error)
+		wantErr bool
+	}{
+		{"EncodeWriter", func() ([]byte, error) {
+			var buf bytes.Buffer
+			err := reg.EncodeWriter(&buf, doc)
+			return buf.Bytes(), err
+		}, false},
+		{"EncodeFile", func() ([]byte, error) {
+			path := filepath.Join(dir, "config.json")
+			if err := reg.EncodeFile(path, doc); err != nil {
+				return nil, err
+			}
+			b, err := os.ReadFile(path)
+			return bytes.TrimSuffix(b, []byte("\n")), err
+		}, false},
+		{"EncodeFile/missing dir", func() ([]byte, error) {
+			return nil, reg.EncodeFile(filepath.Join(dir, "missing", "config.json"), doc)
+		}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.save()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("save succeeded: %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Fatalf("saved %s\nwant  %s", got, want)
+			}
+			// the saved document loads to the same values
+			var again jwalk.Document
+			if err := reg.Unmarshal(got, &again); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again, doc) {
+				t.Errorf("reloaded %v, want %v", again, doc)
+			}
+		})
+	}
+}
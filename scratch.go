@@ -0,0 +1,37 @@
+package jwalk
+
+import "github.com/go-json-experiment/json/jsontext"
+
+// WithScratch gives every decode its own scratch map, which the directives
+// invoked during that decode can share through Scratch, e.g. a symbol table
+// populated by one directive and read by another.
+func WithScratch() RegistryOption {
+	return func(o *RegistryOptions) error {
+		o.Scratch = true
+		return nil
+	}
+}
+
+// Scratch returns the scratch map of the decode driven by dec, for use by a
+// directive's decode function. Each call to one of the Registry's decode
+// methods (Unmarshal, Decode and their variants) gets one map, created empty
+// and discarded when the call returns, so it is never shared between
+// concurrent decodes and needs no locking. Every directive the call invokes
+// sees the same map, including those in included files and in struct fields
+// decoded around jwalk's values. As with context.Context values, keys should
+// be of an unexported type to avoid collisions between directives.
+//
+// Scratch returns nil unless the Registry was built with WithScratch (or the
+// decode is one of the per-call modes such as UnmarshalWithStats). Decoding
+// with json.Unmarshal and the Registry's Options instead gives each outermost
+// value jwalk decodes its own map.
+func Scratch(dec *jsontext.Decoder) map[any]any {
+	st := stateOf(dec)
+	if st == nil {
+		return nil
+	}
+	if st.scratch == nil {
+		st.scratch = make(map[any]any)
+	}
+	return st.scratch
+}
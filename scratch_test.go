@@ -0,0 +1,84 @@
+package jwalk_test
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+type counterKey struct{}
+
+// counter is a directive that numbers its invocations within a decode,
+// keeping the count in the decode's scratch map.
+var counter = jwalk.NewDirective("count", func(dec *jsontext.Decoder) (int, error) {
+	if err := dec.SkipValue(); err != nil {
+		return 0, err
+	}
+	s := jwalk.Scratch(dec)
+	if s == nil {
+		return -1, nil
+	}
+	n, _ := s[counterKey{}].(int)
+	n++
+	s[counterKey{}] = n
+	return n, nil
+})
+
+func TestScratchPerCall(t *testing.T) {
+	for _, opt := range []jwalk.Option{jwalk.WithScratch(), jwalk.WithSentinelScan()} {
+		reg, err := jwalk.NewRegistry(counter, jwalk.WithScratch(), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		const in = `{"A": {"$count": 0}, "B": [{"$count": 0}, {"$count": 0}], "C": {"x": {"$count": 0}}}`
+		tests := []struct {
+			name string
+			out  func() any
+			get  func(any) []any
+		}{
+			{"any", func() any { return new(any) }, func(v any) []any {
+				d := (*v.(*any)).(jwalk.Document)
+				b := d[1].Value.(jwalk.Array)
+				return []any{d[0].Value, b[0], b[1], d[2].Value.(jwalk.Document)[0].Value}
+			}},
+			{"map[string]any", func() any { return new(map[string]any) }, func(v any) []any {
+				m := *v.(*map[string]any)
+				b := m["B"].(jwalk.Array)
+				return []any{m["A"], b[0], b[1], m["C"].(jwalk.Document)[0].Value}
+			}},
+			{"struct", func() any {
+				return new(struct {
+					A any
+					B []any
+					C map[string]any
+				})
+			}, func(v any) []any {
+				s := v.(*struct {
+					A any
+					B []any
+					C map[string]any
+				})
+				return []any{s.A, s.B[0], s.B[1], s.C["x"]}
+			}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				for range 2 { // each call starts afresh
+					out := tt.out()
+					if err := reg.Unmarshal([]byte(in), out); err != nil {
+						t.Fatal(err)
+					}
+					got := tt.get(out)
+					for i, n := range got {
+						if n != i+1 {
+							t.Fatalf("counts = %v, want 1 to %d in order", got, len(got))
+						}
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package jwalk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// SecretProvider resolves secret references, e.g. against a vault or cloud
+// secret manager.
+type SecretProvider interface {
+	Resolve(ref string) (string, error)
+}
+
+// NewSecretDirective constructs a Directive registered under the given name
+// that decodes values of the form:
+//
+//	{"$secret": "db/password"}
+//
+// by passing the reference to p.Resolve, keeping plaintext secrets out of
+// configuration files. A failed resolution is reported with the reference but
+// never a resolved value.
+func NewSecretDirective(name string, p SecretProvider) *Directive {
+	return NewDirective(name, func(dec *jsontext.Decoder) (Secret, error) {
+		var ref string
+		if err := json.UnmarshalDecode(dec, &ref); err != nil {
+			return Secret{}, err
+		}
+		if ref == "" {
+			return Secret{}, errors.New("secret reference is empty")
+		}
+		v, err := p.Resolve(ref)
+		if err != nil {
+			return Secret{}, fmt.Errorf("resolve secret %q: %w", ref, err)
+		}
+		return Secret{name: name, ref: ref, value: v}, nil
+	})
+}
+
+// Secret is a resolved secret produced by a secret directive. It formats as
+// "[redacted]" so it does not leak into logs or Document.String output; use
+// Value to obtain the value.
+//
+// It encodes as the sentinel it was decoded from, e.g.
+// {"$secret": "db/password"}, with the name NewSecretDirective was given, so a
+// document that is decoded, modified and encoded again keeps its reference
+// rather than the secret. A Registry built
+// with WithSecretsRevealed encodes the value in plaintext instead. The zero
+// Secret encodes as null.
+type Secret struct {
+	name  string // directive that resolved it
+	ref   string
+	value string
+}
+
+// Value returns the resolved secret.
+func (s Secret) Value() string {
+	return s.value
+}
+
+// Ref returns the reference the secret was resolved from, e.g. "db/password".
+func (s Secret) Ref() string {
+	return s.ref
+}
+
+// String returns "[redacted]".
+func (s Secret) String() string {
+	return "[redacted]"
+}
+
+// GoString returns "[redacted]", covering the %#v verb.
+func (s Secret) GoString() string {
+	return "[redacted]"
+}
+
+// MarshalJSONTo implements json.MarshalerTo, encoding s as the sentinel
+// object it was decoded from.
+func (s Secret) MarshalJSONTo(enc *jsontext.Encoder) error {
+	if s.name == "" {
+		return enc.WriteToken(jsontext.Null)
+	}
+	if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+		return fmt.Errorf("write object open: %w", err)
+	}
+	if err := enc.WriteToken(jsontext.String("$" + s.name)); err != nil {
+		return fmt.Errorf("write object key %q: %w", "$"+s.name, err)
+	}
+	if err := enc.WriteToken(jsontext.String(s.ref)); err != nil {
+		return fmt.Errorf("write secret reference: %w", err)
+	}
+	if err := enc.WriteToken(jsontext.EndObject); err != nil {
+		return fmt.Errorf("write object close: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package jwalk_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-json-experiment/json"
+
+	"github.com/calumari/jwalk"
+)
+
+type secrets map[string]string
+
+func (s secrets) Resolve(ref string) (string, error) {
+	v, ok := s[ref]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+func TestSecretRedaction(t *testing.T) {
+	d := jwalk.NewSecretDirective("secret", secrets{"db/password": "hunter2"})
+	reg, err := jwalk.NewRegistry(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := []byte(`{"user": "app", "password": {"$secret": "db/password"}}`)
+	var v any
+	if err := reg.Unmarshal(in, &v); err != nil {
+		t.Fatal(err)
+	}
+	pw := v.(jwalk.Document)[1].Value.(jwalk.Secret)
+	if pw.Value() != "hunter2" || pw.Ref() != "db/password" {
+		t.Fatalf("password = %q from %q, want hunter2 from db/password", pw.Value(), pw.Ref())
+	}
+
+	// encoding writes the reference back, so a decoded document can be
+	// modified and saved without losing it or writing the secret out
+	const saved = `{"user":"app","password":{"$secret":"db/password"}}`
+	for _, tt := range []struct {
+		name string
+		reg  *jwalk.Registry
+	}{{"Registry", reg}, {"WithDirectiveEncoding", must(jwalk.With(reg, jwalk.WithDirectiveEncoding()))}} {
+		got, err := tt.reg.Marshal(v)
+		if err != nil || string(got) != saved {
+			t.Errorf("%s: Marshal = %s (%v), want %s", tt.name, got, err, saved)
+			continue
+		}
+		var again any
+		if err := tt.reg.Unmarshal(got, &again); err != nil || again.(jwalk.Document)[1].Value != pw {
+			t.Errorf("%s: Unmarshal(Marshal(v)) = %v (%v), want the same secret", tt.name, again, err)
+		}
+	}
+	if got, err := json.Marshal(struct{ Password, Unset jwalk.Secret }{Password: pw}); err != nil || string(got) != `{"Password":{"$secret":"db/password"},"Unset":null}` {
+		t.Errorf("json.Marshal = %s (%v), want the reference", got, err)
+	}
+	for _, s := range []string{fmt.Sprint(pw), fmt.Sprintf("%#v", pw), v.(jwalk.Document).String()} {
+		if strings.Contains(s, "hunter2") {
+			t.Errorf("formatted output %q leaks the secret", s)
+		}
+	}
+
+	revealing, err := jwalk.With(reg, jwalk.WithSecretsRevealed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const plain = `{"user":"app","password":"hunter2"}`
+	if got, err := revealing.Marshal(v); err != nil || string(got) != plain {
+		t.Errorf("Marshal with WithSecretsRevealed = %s (%v), want %s", got, err, plain)
+	}
+
+	err = reg.Unmarshal([]byte(`{"a": {"$secret": "missing"}}`), &v)
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("Unmarshal error = %v, want the reference", err)
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
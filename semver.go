@@ -0,0 +1,167 @@
+package jwalk
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// StdSemVerDirective constructs a Directive that decodes values of the form:
+//
+//	{"$std.semver": "1.2.3-rc.1+build.5"}
+//
+// into a SemVer, following Semantic Versioning 2.0.0. Invalid versions produce
+// an error. An optional leading "v" is accepted.
+var StdSemVerDirective = NewDirective("std.semver", unmarshalSemVer)
+
+// SemVer is a parsed semantic version produced by StdSemVerDirective.
+type SemVer struct {
+	Major, Minor, Patch uint64
+	Prerelease          []string // dot-separated pre-release identifiers, e.g. ["rc", "1"]
+	Build               []string // dot-separated build metadata identifiers
+}
+
+// String returns the canonical form of v, e.g. "1.2.3-rc.1+build.5".
+func (v SemVer) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		b.WriteByte('-')
+		b.WriteString(strings.Join(v.Prerelease, "."))
+	}
+	if len(v.Build) > 0 {
+		b.WriteByte('+')
+		b.WriteString(strings.Join(v.Build, "."))
+	}
+	return b.String()
+}
+
+// Compare returns -1, 0 or +1 depending on whether v has lower, equal or
+// higher precedence than w. Build metadata is ignored, as the specification
+// requires.
+func (v SemVer) Compare(w SemVer) int {
+	if c := cmp.Compare(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+
+	// a version without pre-release has higher precedence than one with
+	switch {
+	case len(v.Prerelease) == 0 && len(w.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(w.Prerelease) == 0:
+		return -1
+	}
+
+	for i := range min(len(v.Prerelease), len(w.Prerelease)) {
+		if c := comparePrerelease(v.Prerelease[i], w.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(v.Prerelease), len(w.Prerelease))
+}
+
+// comparePrerelease compares single pre-release identifiers: numeric
+// identifiers compare numerically and have lower precedence than
+// alphanumeric ones, which compare lexically.
+func comparePrerelease(a, b string) int {
+	an, aerr := strconv.ParseUint(a, 10, 64)
+	bn, berr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aerr == nil && berr == nil:
+		return cmp.Compare(an, bn)
+	case aerr == nil:
+		return -1
+	case berr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// ParseSemVer parses a semantic version string. See StdSemVerDirective.
+func ParseSemVer(s string) (SemVer, error) {
+	rest := strings.TrimPrefix(s, "v")
+
+	var v SemVer
+	var build, pre string
+	var hasBuild, hasPre bool
+	rest, build, hasBuild = strings.Cut(rest, "+")
+	rest, pre, hasPre = strings.Cut(rest, "-")
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return SemVer{}, fmt.Errorf("invalid semantic version %q: expected major.minor.patch", s)
+	}
+	for i, dst := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		p := parts[i]
+		if p == "" || (len(p) > 1 && p[0] == '0') {
+			return SemVer{}, fmt.Errorf("invalid semantic version %q: bad numeric component %q", s, p)
+		}
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return SemVer{}, fmt.Errorf("invalid semantic version %q: bad numeric component %q", s, p)
+		}
+		*dst = n
+	}
+
+	if hasPre {
+		ids, err := semverIdentifiers(pre, true)
+		if err != nil {
+			return SemVer{}, fmt.Errorf("invalid semantic version %q: pre-release: %w", s, err)
+		}
+		v.Prerelease = ids
+	}
+	if hasBuild {
+		ids, err := semverIdentifiers(build, false)
+		if err != nil {
+			return SemVer{}, fmt.Errorf("invalid semantic version %q: build: %w", s, err)
+		}
+		v.Build = ids
+	}
+	return v, nil
+}
+
+// semverIdentifiers splits and validates dot-separated identifiers. Numeric
+// pre-release identifiers must not have leading zeros.
+func semverIdentifiers(s string, prerelease bool) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("empty identifier")
+		}
+		numeric := true
+		for _, c := range id {
+			switch {
+			case c >= '0' && c <= '9':
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return nil, fmt.Errorf("invalid character %q in identifier %q", c, id)
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf("numeric identifier %q has leading zero", id)
+		}
+	}
+	return ids, nil
+}
+
+func unmarshalSemVer(dec *jsontext.Decoder) (SemVer, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return SemVer{}, err
+	}
+	return ParseSemVer(s)
+}
@@ -0,0 +1,86 @@
+package jwalk_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestParseSemVer(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		want    jwalk.SemVer
+		wantErr string
+	}{
+		{"1.2.3", jwalk.SemVer{Major: 1, Minor: 2, Patch: 3}, ""},
+		{"v0.0.0", jwalk.SemVer{}, ""},
+		{"1.2.3-rc.1+build.5", jwalk.SemVer{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}, Build: []string{"build", "5"}}, ""},
+		{"1.0.0-x-y.0", jwalk.SemVer{Major: 1, Prerelease: []string{"x-y", "0"}}, ""},
+		{"1.0.0+001.exp-sha", jwalk.SemVer{Major: 1, Build: []string{"001", "exp-sha"}}, ""},
+		{"1.2", jwalk.SemVer{}, `invalid semantic version "1.2": expected major.minor.patch`},
+		{"1.2.3.4", jwalk.SemVer{}, "expected major.minor.patch"},
+		{"01.2.3", jwalk.SemVer{}, `bad numeric component "01"`},
+		{"1..3", jwalk.SemVer{}, `bad numeric component ""`},
+		{"1.x.3", jwalk.SemVer{}, `bad numeric component "x"`},
+		{"1.2.3-", jwalk.SemVer{}, "pre-release: empty identifier"},
+		{"1.2.3-01", jwalk.SemVer{}, `pre-release: numeric identifier "01" has leading zero`},
+		{"1.2.3+a..b", jwalk.SemVer{}, "build: empty identifier"},
+		{"1.2.3-rc_1", jwalk.SemVer{}, `pre-release: invalid character '_' in identifier "rc_1"`},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := jwalk.ParseSemVer(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSemVer = %v, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseSemVer = %#v, %v, want %#v", got, err, tt.want)
+			}
+			if s := got.String(); s != strings.TrimPrefix(tt.in, "v") {
+				t.Errorf("String = %q, want %q", s, strings.TrimPrefix(tt.in, "v"))
+			}
+		})
+	}
+}
+
+func TestSemVerCompare(t *testing.T) {
+	// in increasing precedence, from the Semantic Versioning specification
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i, a := range ordered {
+		for j, b := range ordered {
+			v, _ := jwalk.ParseSemVer(a)
+			w, _ := jwalk.ParseSemVer(b)
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := v.Compare(w); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+
+	v, _ := jwalk.ParseSemVer("1.0.0+a")
+	w, _ := jwalk.ParseSemVer("1.0.0+b")
+	if got := v.Compare(w); got != 0 {
+		t.Errorf("1.0.0+a.Compare(1.0.0+b) = %d, want 0: build metadata is ignored", got)
+	}
+}
+
+func TestStdSemVerDirective(t *testing.T) {
+	testStdlib(t, []stdlibTest{
+		{"version", `{"$std.semver": "v1.2.3-rc.1"}`, jwalk.SemVer{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}}, ""},
+		{"short name", `{"$semver": "0.1.0"}`, jwalk.SemVer{Minor: 1}, ""},
+		{"invalid", `{"$std.semver": "latest"}`, nil, `invalid semantic version "latest"`},
+		{"not a string", `{"$std.semver": 1.2}`, nil, "cannot unmarshal JSON number into Go string"},
+	})
+}
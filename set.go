@@ -0,0 +1,49 @@
+package jwalk
+
+import (
+	"fmt"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// StdSetDirective constructs a Directive that decodes values of the form:
+//
+//	{"$std.set": ["a", "b", "a"]}
+//
+// into a Set of strings. Duplicate members are collapsed; use NewSetDirective
+// to reject them instead.
+var StdSetDirective = NewSetDirective("std.set", false)
+
+// NewSetDirective constructs a set Directive registered under the given name.
+// See StdSetDirective. If strict is true, a duplicate member produces an
+// error.
+func NewSetDirective(name string, strict bool) *Directive {
+	return NewDirective(name, func(dec *jsontext.Decoder) (Set, error) {
+		return unmarshalSet(dec, strict)
+	})
+}
+
+// Set is a set of strings produced by StdSetDirective.
+type Set map[string]struct{}
+
+// Contains reports whether s has the member v.
+func (s Set) Contains(v string) bool {
+	_, ok := s[v]
+	return ok
+}
+
+func unmarshalSet(dec *jsontext.Decoder, strict bool) (Set, error) {
+	var members []string
+	if err := json.UnmarshalDecode(dec, &members); err != nil {
+		return nil, err
+	}
+	s := make(Set, len(members))
+	for _, m := range members {
+		if _, dup := s[m]; dup && strict {
+			return nil, fmt.Errorf("duplicate set member %q", m)
+		}
+		s[m] = struct{}{}
+	}
+	return s, nil
+}
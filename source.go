@@ -0,0 +1,122 @@
+package jwalk
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// DirectiveSource describes the input consumed by one directive invocation.
+type DirectiveSource struct {
+	Directive string         // fully qualified directive name
+	Offset    int64          // byte offset of Raw in the input, starting at 0
+	Raw       jsontext.Value // exact source text, e.g. "2023-10-01T12:00:00Z" with its quotes
+	Sentinel  jsontext.Value // exact source text of the whole sentinel object
+}
+
+// DirectiveSources maps the JSON Pointer (RFC 6901) of each directive sentinel
+// object, i.e. of the decoded value it produced, to the input its directive
+// consumed.
+type DirectiveSources map[string]DirectiveSource
+
+// UnmarshalWithDirectiveSources decodes in like Unmarshal and additionally
+// reports the exact source text of every directive value, e.g. to re-emit a
+// timestamp as originally written rather than reformatted, or to verify a
+// signature over the original bytes.
+//
+// For a value-mode directive, Raw is the sentinel's value; for an ObjectMode
+// directive, it is the whole sentinel object. Directives invoked while
+// decoding the value of another directive whose input was buffered (ObjectMode
+// directives, or any directive under WithSentinelScan) are not recorded.
+// Source tracking applies only to this call.
+func (r *Registry) UnmarshalWithDirectiveSources(in []byte, out any, opts ...json.Options) (DirectiveSources, error) {
+	st := r.newState()
+	st.sources = make(map[string]sourceSpan)
+	if err := r.decodeSeeded(in, out, st, opts...); err != nil {
+		return nil, err
+	}
+
+	snap := r.snap.Load()
+	srcs := make(DirectiveSources, len(st.sources))
+	for ptr, span := range st.sources {
+		start, raw := trimSpan(in, span.start, span.end)
+		_, sentinel := trimSpan(in, span.objStart, span.objEnd)
+
+		name := span.name
+		if d, err := snap.lookup(name, r.sepByte); err == nil {
+			name = d.name
+		}
+		srcs[ptr] = DirectiveSource{Directive: name, Offset: start, Raw: bytes.Clone(raw), Sentinel: bytes.Clone(sentinel)}
+	}
+	return srcs, nil
+}
+
+// trimSpan returns the value in in[start:end], and its offset, without the
+// separator and whitespace that a recorded span may include before the value
+// and the whitespace after it.
+func trimSpan(in []byte, start, end int64) (int64, []byte) {
+	for start < end && isValuePrefix(in[start]) {
+		start++
+	}
+	return start, bytes.TrimRight(in[start:end], " \t\r\n")
+}
+
+// sourceSpan is the input consumed by a directive invocation, as recorded
+// during decoding.
+type sourceSpan struct {
+	name             string // directive name as written in the sentinel key
+	start, end       int64  // the input the directive consumed
+	objStart, objEnd int64  // the whole sentinel object
+}
+
+// isValuePrefix reports whether c may appear between the previous token and a
+// value.
+func isValuePrefix(c byte) bool {
+	return c == ':' || isKeyPrefix(c)
+}
+
+// recordSource records the input span consumed by the named directive for the
+// sentinel object at ptr, if source tracking is enabled. It is called once the
+// object has been read, so the object spans from objStart to dec's current
+// offset. Spans read through nested decoders over buffered input are not
+// recorded, since their offsets are not relative to the caller's input.
+func (st *decodeState) recordSource(dec *jsontext.Decoder, ptr, name string, objStart, start, end int64) {
+	if st.sources != nil && dec == st.root {
+		st.sources[ptr] = sourceSpan{name: name, start: start, end: end, objStart: objStart, objEnd: dec.InputOffset()}
+	}
+}
+
+// UnmarshalWithSentinels decodes in like Unmarshal and additionally reports
+// each directive sentinel object as written, keyed by the JSON Pointer of the
+// value it decoded to, for inspecting the source of lossy directive results.
+// Sentinels nested within a reported sentinel are left as plain data in it.
+// The sentinels reported are those reported by UnmarshalWithDirectiveSources.
+func (r *Registry) UnmarshalWithSentinels(in []byte, out any, opts ...json.Options) (map[string]Document, error) {
+	srcs, err := r.UnmarshalWithDirectiveSources(in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sentinels := make(map[string]Document, len(srcs))
+	for ptr, src := range srcs {
+		var d Document
+		if err := plainRegistry.Unmarshal(src.Sentinel, &d); err != nil {
+			return nil, &PathError{Pointer: ptr, Err: fmt.Errorf("decode sentinel: %w", err)}
+		}
+		sentinels[ptr] = d
+	}
+	return sentinels, nil
+}
+
+// plainRegistry decodes JSON as plain ordered data, never dispatching a
+// directive.
+var plainRegistry = newPlainRegistry()
+
+// newPlainRegistry returns a Registry that decodes JSON as plain ordered data,
+// never dispatching a directive.
+func newPlainRegistry() *Registry {
+	r := newRegistry()
+	r.directivesDisabled = true
+	return r
+}
@@ -0,0 +1,31 @@
+package jwalk
+
+import "github.com/go-json-experiment/json/jsontext"
+
+// SpliceResult is a directive result that expands in place into several
+// elements when the directive's sentinel is an element of an array decoded by
+// jwalk, that is into any, Array or []any, e.g. for a directive "range"
+// returning SpliceResult{2.0, 3.0, 4.0},
+//
+//	[1, {"$range": [2, 4]}, 5]
+//
+// decodes as Array{1, 2, 3, 4, 5}. An empty SpliceResult removes the element.
+// Anywhere else, such as an object member or the root, a SpliceResult is
+// decoded as an Array (or []any under WithPlainArrays) of its elements.
+type SpliceResult []any
+
+// unsplice converts a SpliceResult directive result that dec has just read
+// outside an array into the value it stands for there.
+func unsplice(dec *jsontext.Decoder, reg *Registry, v any) any {
+	s, ok := v.(SpliceResult)
+	if !ok {
+		return v
+	}
+	if k, _ := dec.StackIndex(dec.StackDepth()); k == '[' {
+		return v // spliced by unmarshalArray
+	}
+	if reg.plainArrays {
+		return []any(s)
+	}
+	return Array(s)
+}
@@ -0,0 +1,77 @@
+package jwalk_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+// rangeDirective expands {"$range": [lo, hi]} into the numbers lo to hi.
+var rangeDirective = jwalk.NewDirective("range", func(dec *jsontext.Decoder) (jwalk.SpliceResult, error) {
+	var bounds [2]float64
+	if err := json.UnmarshalDecode(dec, &bounds); err != nil {
+		return nil, err
+	}
+	if bounds[0] > bounds[1] {
+		return nil, errors.New("empty range")
+	}
+	var s jwalk.SpliceResult
+	for f := bounds[0]; f <= bounds[1]; f++ {
+		s = append(s, f)
+	}
+	return s, nil
+})
+
+func TestSplice(t *testing.T) {
+	const in = `[1, {"$range": [2, 4]}, 5, {"$range": [6, 6]}]`
+	spliced := []any{1.0, 2.0, 3.0, 4.0, 5.0, 6.0}
+	tests := []struct {
+		name string
+		opts []jwalk.Option
+		in   string
+		out  func() any
+		want any
+	}{
+		{"any", nil, in, func() any { return new(any) }, jwalk.Array(spliced)},
+		{"any, plain arrays", []jwalk.Option{jwalk.WithPlainArrays()}, in, func() any { return new(any) }, spliced},
+		{"Array", nil, in, func() any { return new(jwalk.Array) }, jwalk.Array(spliced)},
+		{"[]any", nil, in, func() any { return new([]any) }, spliced},
+		{"[]any field", nil, `{"A": ` + in + `}`, func() any { return new(struct{ A []any }) }, struct{ A []any }{spliced}},
+		{"map[string][]any", nil, `{"a": ` + in + `}`, func() any { return new(map[string][]any) }, map[string][]any{"a": spliced}},
+		// outside an array, a SpliceResult is an array of its own
+		{"map[string]any", nil, `{"a": {"$range": [1, 2]}}`, func() any { return new(map[string]any) }, map[string]any{"a": jwalk.Array{1.0, 2.0}}},
+		{"root", nil, `{"$range": [1, 2]}`, func() any { return new(any) }, jwalk.Array{1.0, 2.0}},
+		{"nested", nil, `[[{"$range": [1, 2]}], {"a": [{"$range": [3, 3]}]}]`, func() any { return new([]any) },
+			[]any{jwalk.Array{1.0, 2.0}, jwalk.Document{{Key: "a", Value: jwalk.Array{3.0}}}}},
+		{"null []any", nil, `null`, func() any { return new([]any) }, []any(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(append(tt.opts, rangeDirective)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := tt.out()
+			if err := reg.Unmarshal([]byte(tt.in), out); err != nil {
+				t.Fatal(err)
+			}
+			if got := reflect.ValueOf(out).Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	reg, err := jwalk.NewRegistry(rangeDirective)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v []any
+	if err := reg.Unmarshal([]byte(`[1, {"$range": [3, 2]}]`), &v); err == nil {
+		t.Error("Unmarshal of an empty range succeeded")
+	}
+}
@@ -0,0 +1,369 @@
+package jwalk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// decodeState carries the Registry and any scratch state scoped to a single
+// top-level decode.
+//
+// The Registry's decode methods (Unmarshal, Decode and their variants) create
+// one state per call when the Registry or the call needs one, and decode with
+// unmarshalers bound to it (see decodeFrom), so every value of the decode
+// shares it: struct fields and map values decoded by the json package around
+// jwalk's, and values on the nested decoders that directives create with the
+// enclosing decoder's options.
+//
+// Decodes started elsewhere, with json.Unmarshal and the Registry's Options,
+// key their state by the *jsontext.Decoder driving the decode: the outermost
+// jwalk unmarshaler invoked on a decoder creates it, nested invocations on the
+// same decoder (or on a nested decoder created by newSubDecoder) share it, and
+// it is discarded when the outermost call returns. When the root value is not
+// itself decoded by jwalk (e.g. a struct with Document fields), each
+// jwalk-decoded field then gets its own state. Registries with no stateful
+// options share a single state that is never modified.
+type decodeState struct {
+	reg   *Registry
+	bound *json.Unmarshalers // unmarshalers bound to this state, if any (decodeFrom)
+
+	keys map[string]string // interned object keys (WithStringInterning)
+	buf  []byte            // scratch buffer for unquoting interned keys
+
+	positions map[string]int64 // entry pointer -> offset before its key (UnmarshalWithPositions)
+
+	values int // values decoded so far, counted when WithMaxTotalValues is set
+
+	stats *Stats // decode statistics (UnmarshalWithStats)
+
+	scratch map[any]any // directive scratch data, created on first use (Scratch)
+
+	allowed map[string]bool // per-call directive allowlist; nil allows all (UnmarshalAllowing)
+
+	collecting bool         // record recoverable errors rather than fail (WithErrorCollection)
+	errs       []*PathError // recoverable errors recorded so far
+
+	root    *jsontext.Decoder     // decoder over the caller's input
+	sources map[string]sourceSpan // sentinel pointer -> directive input span (UnmarshalWithDirectiveSources)
+
+	includes map[*includer][]string // files being included, outermost first (NewIncludeDirective)
+
+	chargedDec *jsontext.Decoder // decoder and offset of the value charged last,
+	chargedOff int64             // so a value reached twice is charged once
+}
+
+// ErrMaxTotalValues is returned (wrapped) when a decode exceeds the budget set
+// by WithMaxTotalValues.
+var ErrMaxTotalValues = errors.New("max total values exceeded")
+
+// ErrMaxStringLen is returned (wrapped) when a decode meets a string longer
+// than the limit set by WithMaxStringLen.
+var ErrMaxStringLen = errors.New("max string length exceeded")
+
+// decodeStates maps each in-flight *jsontext.Decoder to its *decodeState.
+var decodeStates sync.Map
+
+// boundStates maps the unmarshalers bound to each in-flight decode state (see
+// decodeFrom) to that state.
+var boundStates sync.Map
+
+func noRelease() {}
+
+// acquireState returns the decode state for dec: bound, the state the calling
+// unmarshaler is bound to, if any, or else the state registered for dec,
+// which is created if dec has none. The returned release func must be called
+// when the caller returns; it discards the state if this call created it.
+func (r *Registry) acquireState(dec *jsontext.Decoder, bound *decodeState) (*decodeState, func()) {
+	if bound != nil {
+		return bound, noRelease
+	}
+	if !r.stateful() && r.seeded.Load() == 0 {
+		return r.stateless, noRelease
+	}
+	if st, ok := decodeStates.Load(dec); ok {
+		return st.(*decodeState), noRelease
+	}
+	if !r.stateful() {
+		return r.stateless, noRelease
+	}
+
+	st := r.newState()
+	st.root = dec
+	decodeStates.Store(dec, st)
+	return st, func() { decodeStates.Delete(dec) }
+}
+
+// stateOf returns the state of the decode driven by dec, for code called by
+// it such as a directive's decode function, or nil if the decode has no
+// state of its own.
+func stateOf(dec *jsontext.Decoder) *decodeState {
+	var st any
+	if u, ok := json.GetOption(dec.Options(), json.WithUnmarshalers); ok {
+		st, ok = boundStates.Load(u)
+	}
+	if st == nil {
+		st, _ = decodeStates.Load(dec)
+	}
+	if st, ok := st.(*decodeState); ok && st != st.reg.stateless {
+		return st
+	}
+	return nil
+}
+
+// newSubDecoder returns a decoder over rd with the options of dec, followed
+// by opts, for decoding input that belongs to dec's decode, such as a
+// buffered value or an included file, as part of it: values it decodes share
+// the decode's state. The returned func must be called when the decoder is
+// no longer used.
+func newSubDecoder(dec *jsontext.Decoder, rd io.Reader, opts ...json.Options) (*jsontext.Decoder, func()) {
+	sub := jsontext.NewDecoder(rd, append([]json.Options{dec.Options()}, opts...)...)
+	v, ok := decodeStates.Load(dec)
+	if !ok {
+		return sub, noRelease // none, or bound to sub's unmarshalers
+	}
+	st := v.(*decodeState)
+	st.reg.seeded.Add(1)
+	decodeStates.Store(sub, st)
+	return sub, func() {
+		decodeStates.Delete(sub)
+		st.reg.seeded.Add(-1)
+	}
+}
+
+// stateful reports whether decodes need per-decode state.
+func (r *Registry) stateful() bool {
+	return r.internKeys || r.maxTotalValues > 0 || r.scratch || r.requireObjectRoot || r.snap.Load().stateful > 0
+}
+
+// ownState gives dec a decode state of its own if the Registry needs one and
+// dec has none, as when a directive is invoked through InvokeDirective on a
+// decoder of the caller's rather than by a decode, so that what the directive
+// decodes, on dec or on decoders created with newSubDecoder, shares a state,
+// e.g. the chain of files being included. The returned func must be called
+// when the directive returns.
+func (r *Registry) ownState(dec *jsontext.Decoder) func() {
+	if !r.stateful() || stateOf(dec) != nil {
+		return noRelease
+	}
+	st := r.newState()
+	r.seeded.Add(1)
+	decodeStates.Store(dec, st)
+	return func() {
+		decodeStates.Delete(dec)
+		r.seeded.Add(-1)
+	}
+}
+
+// newState returns a fresh decode state configured from the Registry.
+func (r *Registry) newState() *decodeState {
+	st := &decodeState{reg: r}
+	if r.internKeys {
+		st.keys = make(map[string]string)
+	}
+	return st
+}
+
+// decodeSeeded decodes in into out as a single top-level decode with state
+// st. See decodeFrom.
+func (r *Registry) decodeSeeded(in []byte, out any, st *decodeState, opts ...json.Options) error {
+	return r.decodeFrom(bytes.NewReader(in), out, st, opts...)
+}
+
+// decodeFrom decodes the single value read from rd into out as a single
+// top-level decode with state st, using unmarshalers bound to st followed by
+// opts. st is also registered for the root decoder, for when opts replace the
+// unmarshalers, e.g. with Combine.
+func (r *Registry) decodeFrom(rd io.Reader, out any, st *decodeState, opts ...json.Options) error {
+	u := r.unmarshalers(st)
+	st.bound = u
+	dec := jsontext.NewDecoder(rd, append([]json.Options{json.WithUnmarshalers(u)}, opts...)...)
+	st.root = dec
+	st.collecting = r.collectErrors
+	boundStates.Store(u, st)
+	r.seeded.Add(1)
+	decodeStates.Store(dec, st)
+	defer func() {
+		decodeStates.Delete(dec)
+		r.seeded.Add(-1)
+		boundStates.Delete(u)
+	}()
+
+	return st.collected(decodeAll(dec, out))
+}
+
+// decodeAll decodes the single value read by dec into out, requiring nothing
+// but whitespace after it.
+func decodeAll(dec *jsontext.Decoder, out any) error {
+	if err := json.UnmarshalDecode(dec, out); err != nil {
+		return nestedError(err)
+	}
+	if _, err := dec.ReadToken(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+		return err
+	}
+	return nil
+}
+
+// invokeRaw invokes the named directive on a buffered value, using a nested
+// decoder that shares st and the options of the enclosing decoder dec. base
+// is the pointer of the value, onto which the paths of any errors collected
+// within it are rebased.
+func (st *decodeState) invokeRaw(dec *jsontext.Decoder, name string, raw jsontext.Value, base string) (any, error) {
+	st.countDirective(name)
+	sub, release := newSubDecoder(dec, bytes.NewReader(raw))
+
+	nerrs := len(st.errs)
+	defer func() {
+		release()
+		for i, pe := range st.errs[nerrs:] {
+			st.errs[nerrs+i] = &PathError{Pointer: base + pe.Pointer, Err: pe.Err}
+		}
+	}()
+
+	return st.reg.InvokeDirective(name, sub)
+}
+
+// charge counts the next value in dec against the WithMaxTotalValues budget.
+// Objects and arrays are charged when opened; primitives are charged by their
+// enclosing container (see chargeChild), and values decoded by the json
+// package by chargeValues. A value may be reached by more than one of these,
+// e.g. an object member decoded into any; it is charged only once.
+func (st *decodeState) charge(dec *jsontext.Decoder) error {
+	if st.reg.maxTotalValues <= 0 {
+		return nil
+	}
+	off := dec.InputOffset()
+	if dec == st.chargedDec && off == st.chargedOff {
+		return nil
+	}
+	st.chargedDec, st.chargedOff = dec, off
+	st.values++
+	if st.values > st.reg.maxTotalValues {
+		return fmt.Errorf("%w (limit %d)", ErrMaxTotalValues, st.reg.maxTotalValues)
+	}
+	return nil
+}
+
+// chargeChild charges the next value in dec if it is a primitive, counting it
+// in the decode's Stats if collected.
+func (st *decodeState) chargeChild(dec *jsontext.Decoder) error {
+	if st.reg.maxTotalValues <= 0 && st.stats == nil {
+		return nil
+	}
+	if k := dec.PeekKind(); k == '{' || k == '[' {
+		return nil
+	}
+	if st.stats != nil {
+		st.stats.Primitives++
+	}
+	return st.charge(dec)
+}
+
+// transform checks v, which dec has just decoded from a value of the given
+// kind, against WithMaxStringLen and applies the WithValueTransform function
+// to it. Containers are returned unchanged.
+func (st *decodeState) transform(dec *jsontext.Decoder, kind jsontext.Kind, v any) (any, error) {
+	if s, ok := v.(string); ok && kind == '"' {
+		if err := st.checkStringLen(dec, s); err != nil {
+			return nil, err
+		}
+	}
+	if st.reg.valueTransform == nil || kind == '{' || kind == '[' {
+		return v, nil
+	}
+	tv, err := st.reg.valueTransform(v)
+	if err != nil {
+		err = &PathError{Pointer: string(dec.StackPointer()), Err: fmt.Errorf("transform value: %w", err)}
+		if st.collect(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return tv, nil
+}
+
+// sentinelKey reports whether the object key names a directive to dispatch:
+// it starts with "$" and the directive is allowed by the allow and deny lists
+// in effect. A key naming a directive that is not allowed is an error, unless
+// WithDisallowedAsData makes it plain data. So is a bare "$", unless
+// WithEmptyDirectiveAsLiteral makes it an ordinary key.
+func (st *decodeState) sentinelKey(key string) (bool, error) {
+	if key == "" || key[0] != '$' {
+		return false, nil
+	}
+	reg := st.reg
+	if key == "$" {
+		if reg.emptyAsLiteral {
+			return false, nil
+		}
+		return false, errors.New("empty directive name")
+	}
+	if reg.allowed == nil && reg.denied == nil && st.allowed == nil {
+		return true, nil
+	}
+
+	name := key[1:]
+	if d, err := reg.snap.Load().lookup(name, reg.sepByte); err == nil {
+		name = d.name
+	} else if reg.allowed == nil && st.allowed == nil {
+		return true, nil // not denied; let dispatch report the failed lookup
+	}
+	if (reg.allowed == nil || reg.allowed[name]) && (st.allowed == nil || st.allowed[name]) && !reg.denied[name] {
+		return true, nil
+	}
+	if reg.disallowedAsData {
+		return false, nil
+	}
+	return false, fmt.Errorf("directive %q is not allowed", name)
+}
+
+// checkStringLen reports an error locating the string s just read by dec if
+// it exceeds the WithMaxStringLen limit.
+func (st *decodeState) checkStringLen(dec *jsontext.Decoder, s string) error {
+	if st.reg.maxStringLen > 0 && len(s) > st.reg.maxStringLen {
+		return &PathError{
+			Pointer: string(dec.StackPointer()),
+			Err:     fmt.Errorf("%w: %d bytes (limit %d)", ErrMaxStringLen, len(s), st.reg.maxStringLen),
+		}
+	}
+	return nil
+}
+
+// readKey reads an object member name, interning it if enabled.
+func (st *decodeState) readKey(dec *jsontext.Decoder) (string, error) {
+	if st.keys == nil {
+		var k string
+		if err := json.UnmarshalDecode(dec, &k); err != nil {
+			return "", err
+		}
+		return k, st.checkStringLen(dec, k)
+	}
+
+	raw, err := dec.ReadValue()
+	if err != nil {
+		return "", err
+	}
+	if raw.Kind() != '"' {
+		return "", fmt.Errorf("expected string object name, got %v", raw.Kind())
+	}
+	if st.buf, err = jsontext.AppendUnquote(st.buf[:0], raw); err != nil {
+		return "", err
+	}
+	if st.reg.maxStringLen > 0 && len(st.buf) > st.reg.maxStringLen {
+		return "", st.checkStringLen(dec, string(st.buf))
+	}
+	if k, ok := st.keys[string(st.buf)]; ok {
+		return k, nil
+	}
+	k := string(st.buf)
+	st.keys[k] = k
+	return k, nil
+}
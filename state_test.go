@@ -0,0 +1,203 @@
+package jwalk_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestMaxTotalValues(t *testing.T) {
+	reg, err := jwalk.NewRegistry(jwalk.WithMaxTotalValues(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := "[" + strings.Repeat("1,", 1000) + "1]"
+	nested := "[" + strings.Repeat("[1,2],", 10) + "[1,2]]"
+	tests := []struct {
+		name string
+		in   string
+		out  func() any
+	}{
+		{"any", long, func() any { return new(any) }},
+		{"[]any", long, func() any { return new([]any) }},
+		{"[]any of arrays", nested, func() any { return new([]any) }},
+		{"[]int", long, func() any { return new([]int) }},
+		{"map[string]any", `{"a":1,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":8,"i":9,"j":10}`, func() any { return new(map[string]any) }},
+		{"map[string]any of objects", `{"a":{"x":1,"y":2},"b":{"x":1,"y":2},"c":{"x":1,"y":2},"d":{"x":1}}`, func() any { return new(map[string]any) }},
+		{"struct", `{"A":[1,2,3,4],"B":[1,2,3,4],"C":{"x":1,"y":2}}`, func() any {
+			return new(struct {
+				A, B jwalk.Array
+				C    jwalk.Document
+			})
+		}},
+		{"struct of primitives", `{"A":1,"B":2,"C":3,"D":4,"E":5,"F":6,"G":7,"H":8,"I":9,"J":10}`, func() any {
+			return new(struct{ A, B, C, D, E, F, G, H, I, J int })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := reg.Unmarshal([]byte(tt.in), tt.out()); !errors.Is(err, jwalk.ErrMaxTotalValues) {
+				t.Errorf("Unmarshal error = %v, want ErrMaxTotalValues", err)
+			}
+			if err := reg.Decode(strings.NewReader(tt.in), tt.out()); !errors.Is(err, jwalk.ErrMaxTotalValues) {
+				t.Errorf("Decode error = %v, want ErrMaxTotalValues", err)
+			}
+		})
+	}
+}
+
+func TestMaxTotalValuesWithinBudget(t *testing.T) {
+	reg, err := jwalk.NewRegistry(jwalk.WithMaxTotalValues(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each value counts once, however many unmarshalers it passes through
+	tests := []struct {
+		name string
+		in   string
+		out  any
+	}{
+		{"any", `{"a":[1,2,3],"b":{"c":null,"d":"x"},"e":true}`, new(any)},
+		{"[]any", `[1,[2,3],{"a":4},5,6]`, new([]any)},
+		{"map[string]any", `{"a":1,"b":[2,3],"c":{"d":4}}`, new(map[string]any)},
+		{"struct", `{"A":[1,2],"B":{"x":1},"C":3}`, new(struct {
+			A jwalk.Array
+			B jwalk.Document
+			C int
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := reg.Unmarshal([]byte(tt.in), tt.out); err != nil {
+				t.Errorf("Unmarshal error = %v", err)
+			}
+		})
+	}
+}
+
+// anyDirective decodes its value into any, so a nested array is decoded by
+// jwalk on the decoder the directive is handed.
+var anyDirective = jwalk.NewDirective("wrap", func(dec *jsontext.Decoder) (any, error) {
+	var v any
+	err := json.UnmarshalDecode(dec, &v)
+	return v, err
+})
+
+func TestRequireObjectRoot(t *testing.T) {
+	for _, opts := range [][]jwalk.Option{
+		{jwalk.WithRequireObjectRoot()},
+		{jwalk.WithRequireObjectRoot(), jwalk.WithSentinelScan()},
+	} {
+		reg, err := jwalk.NewRegistry(append(opts, jwalk.StdJSONDirective, anyDirective)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, in := range []string{`[1]`, `"a"`, `1`, `null`} {
+			var v any
+			if err := reg.Unmarshal([]byte(in), &v); err == nil || !strings.Contains(err.Error(), "root must be a JSON object") {
+				t.Errorf("Unmarshal(%s) error = %v, want root error", in, err)
+			}
+			if err := reg.Decode(strings.NewReader(in), &v); err == nil || !strings.Contains(err.Error(), "root must be a JSON object") {
+				t.Errorf("Decode(%s) error = %v, want root error", in, err)
+			}
+			if err := json.Unmarshal([]byte(in), &v, reg.Options()...); err == nil || !strings.Contains(err.Error(), "root must be a JSON object") {
+				t.Errorf("json.Unmarshal(%s) error = %v, want root error", in, err)
+			}
+		}
+
+		// the root of a value decoded by a directive is not the root of the
+		// input
+		for _, in := range []string{`{"a": {"$std.json": "[1,2]"}}`, `{"a": {"$wrap": [1,2]}}`, `{"a": [{"$wrap": 1}]}`} {
+			var v any
+			if err := reg.Unmarshal([]byte(in), &v); err != nil {
+				t.Errorf("Unmarshal(%s) error = %v", in, err)
+			}
+			if err := json.Unmarshal([]byte(in), &v, reg.Options()...); err != nil {
+				t.Errorf("json.Unmarshal(%s) error = %v", in, err)
+			}
+		}
+	}
+}
+
+// records returns a JSON array of n records sharing the same keys.
+func records(n int) []byte {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := range n {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"id":%d,"name":"item%d","price":9.99,"in_stock":true,"tags":{"color":"red","size":"m"}}`, i, i)
+	}
+	sb.WriteString("]")
+	return []byte(sb.String())
+}
+
+func TestStringInterning(t *testing.T) {
+	in := records(3)
+	keyData := func(reg *jwalk.Registry) []*byte {
+		var v any
+		if err := reg.Unmarshal(in, &v); err != nil {
+			t.Fatal(err)
+		}
+		var ptrs []*byte
+		for _, rec := range v.(jwalk.Array) {
+			d := rec.(jwalk.Document)
+			ptrs = append(ptrs, unsafe.StringData(d[0].Key), unsafe.StringData(d[4].Value.(jwalk.Document)[1].Key))
+		}
+		return ptrs
+	}
+
+	interning, err := jwalk.NewRegistry(jwalk.WithStringInterning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := keyData(interning)
+	for i := 2; i < len(first); i++ {
+		if first[i] != first[i%2] {
+			t.Errorf("record %d: key not shared with record 0", i/2)
+		}
+	}
+	// the table is per decode, so a second decode has keys of its own
+	if second := keyData(interning); second[0] == first[0] {
+		t.Error("key shared across decodes")
+	}
+}
+
+// BenchmarkStringInterning decodes a 50k-record array with and without
+// WithStringInterning; compare B/op.
+func BenchmarkStringInterning(b *testing.B) {
+	in := records(50_000)
+	for _, bm := range []struct {
+		name string
+		opts []jwalk.Option
+	}{
+		{"Plain", nil},
+		{"Interning", []jwalk.Option{jwalk.WithStringInterning()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			reg, err := jwalk.NewRegistry(bm.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(in)))
+			b.ReportAllocs()
+			for b.Loop() {
+				var v any
+				if err := reg.Unmarshal(in, &v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
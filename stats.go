@@ -0,0 +1,58 @@
+package jwalk
+
+import (
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// Stats summarizes the work done by a single decode. See
+// Registry.UnmarshalWithStats.
+type Stats struct {
+	Objects    int            // JSON objects decoded, including directive sentinel objects
+	Arrays     int            // JSON arrays decoded
+	Primitives int            // strings, numbers, booleans and nulls within objects and arrays
+	Directives map[string]int // directive invocations by fully qualified name
+	MaxDepth   int            // deepest object/array nesting reached; a top-level object is depth 1
+}
+
+// UnmarshalWithStats decodes in like Unmarshal and additionally reports
+// statistics about the decode, e.g. to see which directives dominate or
+// whether documents nest deeper than expected.
+//
+// Values are counted as jwalk decodes them: containers decoded into Document,
+// Array or any, and the primitives directly within them. Collection applies
+// only to this call, so other decodes pay nothing for it.
+func (r *Registry) UnmarshalWithStats(in []byte, out any, opts ...json.Options) (Stats, error) {
+	st := r.newState()
+	st.stats = &Stats{Directives: make(map[string]int)}
+	if err := r.decodeSeeded(in, out, st, opts...); err != nil {
+		return Stats{}, err
+	}
+	return *st.stats, nil
+}
+
+// countContainer counts an object or array whose opening token dec has just
+// read, if statistics are being collected.
+func (st *decodeState) countContainer(dec *jsontext.Decoder, kind jsontext.Kind) {
+	if st.stats == nil {
+		return
+	}
+	if kind == '{' {
+		st.stats.Objects++
+	} else {
+		st.stats.Arrays++
+	}
+	st.stats.MaxDepth = max(st.stats.MaxDepth, dec.StackDepth())
+}
+
+// countDirective counts an invocation of the named directive, if statistics
+// are being collected. Names that do not resolve are counted as written.
+func (st *decodeState) countDirective(name string) {
+	if st.stats == nil {
+		return
+	}
+	if d, err := st.reg.snap.Load().lookup(name, st.reg.sepByte); err == nil {
+		name = d.name
+	}
+	st.stats.Directives[name]++
+}
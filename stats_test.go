@@ -0,0 +1,50 @@
+package jwalk_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calumari/jwalk"
+)
+
+func TestUnmarshalWithStats(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    []jwalk.Option
+		in      string
+		want    jwalk.Stats
+		wantErr string
+	}{
+		{"empty object", nil, `{}`, jwalk.Stats{Objects: 1, Directives: map[string]int{}, MaxDepth: 1}, ""},
+		{"nested", nil, `{"a": [1, "x", {"b": [null, [true]]}], "c": {}}`, jwalk.Stats{
+			Objects: 3, Arrays: 3, Primitives: 4, Directives: map[string]int{}, MaxDepth: 5,
+		}, ""},
+		{"directives", []jwalk.Option{jwalk.WithStdlib()},
+			`{"t": {"$std.duration": "1s"}, "u": [{"$duration": "2s"}, {"$std.time": "2023-10-01T12:00:00Z"}]}`,
+			jwalk.Stats{Objects: 4, Arrays: 1, Directives: map[string]int{"std.duration": 2, "std.time": 1}, MaxDepth: 3}, ""},
+		{"directive error", []jwalk.Option{jwalk.WithStdlib()}, `{"t": {"$std.duration": "x"}}`, jwalk.Stats{}, `t: directive "std.duration"`},
+		{"invalid", nil, `{"a": [1, 2}`, jwalk.Stats{}, "invalid character '}'"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc jwalk.Document
+			got, err := reg.UnmarshalWithStats([]byte(tt.in), &doc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UnmarshalWithStats = %+v, %v, want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("UnmarshalWithStats = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package jwalk
 
 import (
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-json-experiment/json"
@@ -13,16 +19,24 @@ var (
 	//
 	//	{"$std.time": "2006-01-02T15:04:05Z07:00"}                      // RFC3339 (default)
 	//	{"$std.time": {"value":"2023-10-05","layout":"2006-01-02"}}     // custom layout
+	//	{"$std.time": {"value":"2023-10-05T14:00:00","layout":"2006-01-02T15:04:05","zone":"America/New_York"}}
 	//
 	// When the object form is used, layout is optional and defaults to time.RFC3339.
-	StdTimeDirective = NewDirective("std.time", unmarshalTime)
+	// Zone is optional; when set it is loaded with time.LoadLocation and values
+	// without an explicit offset are interpreted in that location.
+	//
+	// Under WithDirectiveEncoding, a time.Time encodes in the string form, as
+	// RFC 3339 with any fractional seconds.
+	StdTimeDirective = NewRoundTripDirective("std.time", unmarshalTime, marshalTime)
 
 	// DurationDirective constructs a Directive that decodes values of the form:
 	//
 	//	{"$std.duration": "1h30m"}
 	//
-	// into a time.Duration using time.ParseDuration.
-	StdDurationDirective = NewDirective("std.duration", unmarshalDuration)
+	// into a time.Duration using time.ParseDuration. Under
+	// WithDirectiveEncoding, a time.Duration encodes in this form, as
+	// time.Duration.String formats it.
+	StdDurationDirective = NewRoundTripDirective("std.duration", unmarshalDuration, marshalDuration)
 
 	// RegexDirective constructs a Directive that decodes values of the form:
 	//
@@ -30,14 +44,141 @@ var (
 	//
 	// into a *regexp.Regexp using regexp.Compile.
 	StdRegexDirective = NewDirective("std.regex", unmarshalRegex)
+
+	// StdRawDirective constructs a Directive that captures values of the form:
+	//
+	//	{"$std.raw": <any JSON value>}
+	//
+	// as a jsontext.Value holding the exact source bytes of the nested value,
+	// without interpreting it. The captured value may later be decoded with a
+	// different Registry.
+	StdRawDirective = NewDirective("std.raw", unmarshalRaw)
+
+	// StdPointDirective constructs a Directive that decodes values of the form:
+	//
+	//	{"$std.point": {"x": 1.5, "y": 2.0}}
+	//
+	// into a Point. Missing fields default to zero. Unknown fields are ignored
+	// unless decoding with json.RejectUnknownMembers(true), in which case they
+	// produce an error.
+	StdPointDirective = NewDirective("std.point", unmarshalPoint)
+
+	// StdColorDirective constructs a Directive that decodes values of either form:
+	//
+	//	{"$std.color": "#ff8800"}                           // #rgb, #rgba, #rrggbb or #rrggbbaa
+	//	{"$std.color": {"r":255,"g":136,"b":0,"a":255}}     // components 0-255
+	//
+	// into an RGBA. Alpha defaults to 255 (opaque) when omitted in either form.
+	// Malformed hex strings and out-of-range components produce an error.
+	StdColorDirective = NewDirective("std.color", unmarshalColor)
+
+	// StdByteSizeDirective constructs a Directive that decodes values of the form:
+	//
+	//	{"$std.bytesize": "1.5GiB"}
+	//
+	// into an int64 byte count. Both SI units (kB/KB, MB, GB, TB, PB, EB; powers
+	// of 1000) and IEC units (KiB, MiB, GiB, TiB, PiB, EiB; powers of 1024) are
+	// supported, as are "B" and a bare number of bytes. Fractional results are
+	// rounded to the nearest byte. As with any namespaced directive, the short
+	// form {"$bytesize": ...} resolves when unambiguous.
+	StdByteSizeDirective = NewDirective("std.bytesize", unmarshalByteSize)
+
+	// StdIPDirective constructs a Directive that decodes values of the form:
+	//
+	//	{"$std.ip": "192.168.1.1"}        // or an IPv6 address, e.g. "fe80::1%eth0"
+	//
+	// into a netip.Addr using netip.ParseAddr. Invalid addresses produce an
+	// error.
+	StdIPDirective = NewDirective("std.ip", unmarshalIP)
+
+	// StdCIDRDirective constructs a Directive that decodes values of the form:
+	//
+	//	{"$std.cidr": "10.0.0.0/8"}
+	//
+	// into a netip.Prefix using netip.ParsePrefix. Invalid prefixes produce an
+	// error. Host bits are kept as written; use Prefix.Masked to clear them.
+	StdCIDRDirective = NewDirective("std.cidr", unmarshalCIDR)
+
+	// StdFileModeDirective constructs a Directive that decodes values of either
+	// form:
+	//
+	//	{"$std.filemode": "0644"}          // octal, up to 4 digits
+	//	{"$std.filemode": "rwxr-xr-x"}     // symbolic, as printed by ls -l
+	//
+	// into an os.FileMode. The octal form's leading digit and the symbolic
+	// form's s/S and t/T set the setuid, setgid and sticky bits. The symbolic
+	// form may carry a leading "-" file type, as ls prints it. Invalid modes
+	// produce an error.
+	StdFileModeDirective = NewDirective("std.filemode", unmarshalFileMode)
+
+	// StdQuantityDirective constructs a Directive that decodes values of the
+	// form:
+	//
+	//	{"$std.quantity": "9.8 m/s^2"}     // or "5kg", "-40 degC", "1e3 Hz"
+	//
+	// into a Quantity: a decimal number followed by a unit, which is left
+	// uninterpreted. Space between the two is optional, and the unit may be
+	// omitted for a dimensionless quantity. A missing or malformed number
+	// produces an error.
+	StdQuantityDirective = NewDirective("std.quantity", unmarshalQuantity)
+
+	// StdJSONDirective constructs a Directive that decodes values of the form:
+	//
+	//	{"$std.json": "{\"a\": 1}"}
+	//
+	// by decoding the string's contents as JSON in turn, as embedded by some
+	// message queues and webhooks, with the same options and so the same
+	// Registry: an object becomes a Document, an array an Array, and
+	// directive sentinels within are dispatched. Invalid embedded JSON
+	// produces an error locating the problem within the string.
+	StdJSONDirective = NewRawDirective("std.json", unmarshalEmbeddedJSON)
 )
 
+// Stdlib returns the standard directives, registered under the "std"
+// namespace: std.time, std.duration, std.regex, std.raw, std.point,
+// std.color, std.bytesize, std.ip, std.cidr, std.filemode, std.quantity,
+// std.json, std.semver, std.cron, std.map, std.ordered and std.set. See
+// WithStdlib.
+func Stdlib() []*Directive {
+	return []*Directive{
+		StdTimeDirective,
+		StdDurationDirective,
+		StdRegexDirective,
+		StdRawDirective,
+		StdPointDirective,
+		StdColorDirective,
+		StdByteSizeDirective,
+		StdIPDirective,
+		StdCIDRDirective,
+		StdFileModeDirective,
+		StdQuantityDirective,
+		StdJSONDirective,
+		StdSemVerDirective,
+		StdCronDirective,
+		StdMapDirective,
+		StdOrderedDirective,
+		StdSetDirective,
+	}
+}
+
+// Point is a two-dimensional coordinate produced by StdPointDirective.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// RGBA is an 8-bit per channel color produced by StdColorDirective.
+type RGBA struct {
+	R, G, B, A uint8
+}
+
 func unmarshalTime(dec *jsontext.Decoder) (time.Time, error) {
 	// Support object with value/layout or plain string.
 	if dec.PeekKind() == '{' {
 		var aux struct {
 			Value  string `json:"value"`
 			Layout string `json:"layout"`
+			Zone   string `json:"zone"`
 		}
 		if err := json.UnmarshalDecode(dec, &aux); err != nil {
 			return time.Time{}, err
@@ -46,7 +187,14 @@ func unmarshalTime(dec *jsontext.Decoder) (time.Time, error) {
 		if layout == "" {
 			layout = time.RFC3339
 		}
-		return time.Parse(layout, aux.Value)
+		if aux.Zone == "" {
+			return time.Parse(layout, aux.Value)
+		}
+		loc, err := time.LoadLocation(aux.Zone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.ParseInLocation(layout, aux.Value, loc)
 	}
 
 	var value string
@@ -56,6 +204,14 @@ func unmarshalTime(dec *jsontext.Decoder) (time.Time, error) {
 	return time.Parse(time.RFC3339, value)
 }
 
+func marshalTime(enc *jsontext.Encoder, t time.Time) error {
+	return enc.WriteToken(jsontext.String(t.Format(time.RFC3339Nano)))
+}
+
+func marshalDuration(enc *jsontext.Encoder, d time.Duration) error {
+	return enc.WriteToken(jsontext.String(d.String()))
+}
+
 func unmarshalDuration(dec *jsontext.Decoder) (time.Duration, error) {
 	var s string
 	if err := json.UnmarshalDecode(dec, &s); err != nil {
@@ -71,3 +227,304 @@ func unmarshalRegex(dec *jsontext.Decoder) (*regexp.Regexp, error) {
 	}
 	return regexp.Compile(expr)
 }
+
+func unmarshalRaw(dec *jsontext.Decoder) (jsontext.Value, error) {
+	raw, err := dec.ReadValue()
+	if err != nil {
+		return nil, err
+	}
+	// ReadValue's result is only valid until the next decoder call.
+	return raw.Clone(), nil
+}
+
+func unmarshalIP(dec *jsontext.Decoder) (netip.Addr, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return netip.Addr{}, err
+	}
+	return netip.ParseAddr(s)
+}
+
+func unmarshalCIDR(dec *jsontext.Decoder) (netip.Prefix, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.ParsePrefix(s)
+}
+
+func unmarshalFileMode(dec *jsontext.Decoder) (os.FileMode, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return 0, err
+	}
+	return parseFileMode(s)
+}
+
+// parseFileMode parses an octal ("0755") or symbolic ("rwxr-xr-x") mode.
+func parseFileMode(s string) (os.FileMode, error) {
+	if s != "" && s[0] >= '0' && s[0] <= '7' {
+		if len(s) > 4 {
+			return 0, fmt.Errorf("invalid file mode %q: more than 4 octal digits", s)
+		}
+		n, err := strconv.ParseUint(s, 8, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid file mode %q: not octal", s)
+		}
+		mode := os.FileMode(n & 0o777)
+		if n&0o4000 != 0 {
+			mode |= os.ModeSetuid
+		}
+		if n&0o2000 != 0 {
+			mode |= os.ModeSetgid
+		}
+		if n&0o1000 != 0 {
+			mode |= os.ModeSticky
+		}
+		return mode, nil
+	}
+
+	sym := s
+	if len(sym) == 10 && sym[0] == '-' {
+		sym = sym[1:]
+	}
+	if len(sym) != 9 {
+		return 0, fmt.Errorf("invalid file mode %q: expected octal digits or 9 symbolic characters", s)
+	}
+
+	var mode os.FileMode
+	// special is the mode bit for s/S (user, group) or t/T (other) in each
+	// execute position.
+	special := [3]struct {
+		char byte
+		bit  os.FileMode
+	}{{'s', os.ModeSetuid}, {'s', os.ModeSetgid}, {'t', os.ModeSticky}}
+	for i := range 9 {
+		c, bit := sym[i], os.FileMode(1)<<(8-i)
+		want := "rwx"[i%3]
+		switch {
+		case c == '-':
+		case c == want:
+			mode |= bit
+		case i%3 == 2 && c == special[i/3].char:
+			mode |= bit | special[i/3].bit
+		case i%3 == 2 && c == special[i/3].char-'a'+'A':
+			mode |= special[i/3].bit
+		default:
+			return 0, fmt.Errorf("invalid file mode %q: unexpected %q at position %d", s, c, i+1)
+		}
+	}
+	return mode, nil
+}
+
+func unmarshalPoint(dec *jsontext.Decoder) (Point, error) {
+	if k := dec.PeekKind(); k != '{' {
+		return Point{}, fmt.Errorf("expected object, got %v", k)
+	}
+	var p Point
+	if err := json.UnmarshalDecode(dec, &p); err != nil {
+		return Point{}, err
+	}
+	return p, nil
+}
+
+func unmarshalColor(dec *jsontext.Decoder) (RGBA, error) {
+	// Support object with r/g/b/a components or hex string.
+	if dec.PeekKind() == '{' {
+		aux := struct {
+			R int `json:"r"`
+			G int `json:"g"`
+			B int `json:"b"`
+			A int `json:"a"`
+		}{A: 255}
+		if err := json.UnmarshalDecode(dec, &aux); err != nil {
+			return RGBA{}, err
+		}
+		for _, c := range []struct {
+			name string
+			v    int
+		}{{"r", aux.R}, {"g", aux.G}, {"b", aux.B}, {"a", aux.A}} {
+			if c.v < 0 || c.v > 255 {
+				return RGBA{}, fmt.Errorf("color component %s out of range [0, 255]: %d", c.name, c.v)
+			}
+		}
+		return RGBA{R: uint8(aux.R), G: uint8(aux.G), B: uint8(aux.B), A: uint8(aux.A)}, nil
+	}
+
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return RGBA{}, err
+	}
+	return parseHexColor(s)
+}
+
+// parseHexColor parses #rgb, #rgba, #rrggbb and #rrggbbaa colors.
+func parseHexColor(s string) (RGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok {
+		return RGBA{}, fmt.Errorf("invalid hex color %q: missing #", s)
+	}
+
+	var short bool
+	switch len(hex) {
+	case 3, 4:
+		short = true
+	case 6, 8:
+	default:
+		return RGBA{}, fmt.Errorf("invalid hex color %q: expected 3, 4, 6 or 8 digits", s)
+	}
+
+	digits := make([]uint8, len(hex))
+	for i := range len(hex) {
+		d, err := strconv.ParseUint(hex[i:i+1], 16, 8)
+		if err != nil {
+			return RGBA{}, fmt.Errorf("invalid hex color %q: bad digit %q", s, hex[i])
+		}
+		digits[i] = uint8(d)
+	}
+
+	c := [4]uint8{3: 255}
+	if short {
+		for i, d := range digits {
+			c[i] = d<<4 | d
+		}
+	} else {
+		for i := 0; i < len(digits); i += 2 {
+			c[i/2] = digits[i]<<4 | digits[i+1]
+		}
+	}
+	return RGBA{R: c[0], G: c[1], B: c[2], A: c[3]}, nil
+}
+
+// byteUnits maps size unit suffixes to their multipliers.
+var byteUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"kB":  1e3,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"EB":  1e18,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"PiB": 1 << 50,
+	"EiB": 1 << 60,
+}
+
+func unmarshalByteSize(dec *jsontext.Decoder) (int64, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return 0, err
+	}
+	return parseByteSize(s)
+}
+
+// parseByteSize parses a human-readable size such as "256KB" or "1.5 GiB".
+func parseByteSize(s string) (int64, error) {
+	num := strings.TrimSpace(s)
+	end := strings.IndexFunc(num, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	unit := ""
+	if end >= 0 {
+		num, unit = num[:end], strings.TrimSpace(num[end:])
+	}
+	if num == "" {
+		return 0, fmt.Errorf("invalid byte size %q: missing number", s)
+	}
+
+	mult, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, unit)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q: %w", s, err)
+	}
+
+	n := math.Round(f * mult)
+	if n >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid byte size %q: overflows int64", s)
+	}
+	return int64(n), nil
+}
+
+// Quantity is a number with a unit produced by StdQuantityDirective.
+type Quantity struct {
+	Value float64
+	Unit  string // as written, e.g. "m/s^2"; empty if dimensionless
+}
+
+func unmarshalEmbeddedJSON(dec *jsontext.Decoder) (any, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return nil, err
+	}
+	sub, release := newSubDecoder(dec, strings.NewReader(s))
+	defer release()
+	var v any
+	if err := decodeAll(sub, &v); err != nil {
+		return nil, fmt.Errorf("invalid embedded JSON: %w", flattenPath(err))
+	}
+	return v, nil
+}
+
+func unmarshalQuantity(dec *jsontext.Decoder) (Quantity, error) {
+	var s string
+	if err := json.UnmarshalDecode(dec, &s); err != nil {
+		return Quantity{}, err
+	}
+	return parseQuantity(s)
+}
+
+// parseQuantity parses a number followed by an optional unit, e.g. "5kg".
+func parseQuantity(s string) (Quantity, error) {
+	in := strings.TrimSpace(s)
+	end := quantityNumberLen(in)
+	if end == 0 {
+		return Quantity{}, fmt.Errorf("invalid quantity %q: missing number", s)
+	}
+	v, err := strconv.ParseFloat(in[:end], 64)
+	if err != nil {
+		return Quantity{}, fmt.Errorf("invalid quantity %q: %w", s, err)
+	}
+	return Quantity{Value: v, Unit: strings.TrimSpace(in[end:])}, nil
+}
+
+// quantityNumberLen returns the length of the decimal number, with optional
+// sign, fraction and exponent, at the start of s.
+func quantityNumberLen(s string) int {
+	digits := func(i int) int {
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i
+	}
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	start := i
+	i = digits(i)
+	if i < len(s) && s[i] == '.' {
+		i = digits(i + 1)
+	}
+	if i == start || (i == start+1 && s[start] == '.') {
+		return 0 // no digits
+	}
+	// an exponent only if digits follow, so "5e" is 5 in unit "e"
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if k := digits(j); k > j {
+			i = k
+		}
+	}
+	return i
+}
@@ -0,0 +1,194 @@
+package jwalk_test
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // for std.time's zone, wherever the tests run
+
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+// decodeStdlib decodes in with the standard directives and returns the
+// result, or the error's message.
+func decodeStdlib(t *testing.T, in string) (any, string) {
+	t.Helper()
+	reg, err := jwalk.NewRegistry(jwalk.WithStdlib())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := reg.DecodeValue([]byte(in))
+	if err != nil {
+		return nil, err.Error()
+	}
+	return v, ""
+}
+
+// stdlibTest is a case for testStdlib.
+type stdlibTest struct {
+	name    string
+	in      string
+	want    any
+	wantErr string // a substring of the error; empty if none is expected
+}
+
+// testStdlib runs each test in turn, decoding its input with the standard
+// directives.
+func testStdlib(t *testing.T, tests []stdlibTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errMsg := decodeStdlib(t, tt.in)
+			if tt.wantErr != "" {
+				if !strings.Contains(errMsg, tt.wantErr) {
+					t.Fatalf("DecodeValue = %v, %q, want error containing %q", got, errMsg, tt.wantErr)
+				}
+				return
+			}
+			if errMsg != "" {
+				t.Fatal(errMsg)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DecodeValue = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdRawDirective(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"object", `{"$std.raw": {"b": 1, "a": [true, null]}}`, `{"b": 1, "a": [true, null]}`, ""},
+		{"directive not dispatched", `{"$std.raw": {"$std.time": "bad"}}`, `{"$std.time": "bad"}`, ""},
+		{"string", `{"$std.raw": "xA"}`, `"xA"`, ""},
+		{"number", `{"$std.raw": 1e3}`, `1e3`, ""},
+		{"invalid", `{"$std.raw": [1,]}`, "", "invalid character ']'"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errMsg := decodeStdlib(t, tt.in)
+			if tt.wantErr != "" {
+				if !strings.Contains(errMsg, tt.wantErr) {
+					t.Fatalf("DecodeValue = %v, %q, want error containing %q", got, errMsg, tt.wantErr)
+				}
+				return
+			}
+			if errMsg != "" {
+				t.Fatal(errMsg)
+			}
+			if raw, ok := got.(jsontext.Value); !ok || string(raw) != tt.want {
+				t.Fatalf("DecodeValue = %#v, want jsontext.Value(%s)", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdTimeDirectiveZone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantErr string
+	}{
+		{"string", `{"$std.time": "2023-10-05T14:00:00+02:00"}`, time.Date(2023, 10, 5, 12, 0, 0, 0, time.UTC), ""},
+		{"object/default layout", `{"$std.time": {"value": "2023-10-05T14:00:00Z"}}`, time.Date(2023, 10, 5, 14, 0, 0, 0, time.UTC), ""},
+		{"object/layout", `{"$std.time": {"value": "2023-10-05", "layout": "2006-01-02"}}`, time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC), ""},
+		{"zone", `{"$std.time": {"value": "2023-10-05T14:00:00", "layout": "2006-01-02T15:04:05", "zone": "America/New_York"}}`, time.Date(2023, 10, 5, 14, 0, 0, 0, newYork), ""},
+		{"zone/winter", `{"$std.time": {"value": "2023-01-05 14:00", "layout": "2006-01-02 15:04", "zone": "America/New_York"}}`, time.Date(2023, 1, 5, 19, 0, 0, 0, time.UTC), ""},
+		{"zone/explicit offset", `{"$std.time": {"value": "2023-10-05T14:00:00Z", "zone": "America/New_York"}}`, time.Date(2023, 10, 5, 14, 0, 0, 0, time.UTC), ""},
+		{"zone/unknown", `{"$std.time": {"value": "2023-10-05T14:00:00Z", "zone": "Mars/Olympus_Mons"}}`, time.Time{}, "unknown time zone Mars/Olympus_Mons"},
+		{"zone/bad value", `{"$std.time": {"value": "14:00", "zone": "America/New_York"}}`, time.Time{}, `parsing time "14:00"`},
+		{"bad value", `{"$std.time": "2023-10-05"}`, time.Time{}, `parsing time "2023-10-05"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errMsg := decodeStdlib(t, tt.in)
+			if tt.wantErr != "" {
+				if !strings.Contains(errMsg, tt.wantErr) {
+					t.Fatalf("DecodeValue = %v, %q, want error containing %q", got, errMsg, tt.wantErr)
+				}
+				return
+			}
+			if errMsg != "" {
+				t.Fatal(errMsg)
+			}
+			if ts, ok := got.(time.Time); !ok || !ts.Equal(tt.want) {
+				t.Fatalf("DecodeValue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdPointDirective(t *testing.T) {
+	testStdlib(t, []stdlibTest{
+		{"point", `{"$std.point": {"x": 1.5, "y": -2}}`, jwalk.Point{X: 1.5, Y: -2}, ""},
+		{"defaults", `{"$std.point": {"y": 3}}`, jwalk.Point{Y: 3}, ""},
+		{"empty", `{"$std.point": {}}`, jwalk.Point{}, ""},
+		{"unknown ignored", `{"$std.point": {"x": 1, "z": 9}}`, jwalk.Point{X: 1}, ""},
+		{"not an object", `{"$std.point": [1, 2]}`, nil, "expected object, got ["},
+		{"bad field", `{"$std.point": {"x": "1"}}`, nil, "cannot unmarshal JSON string into Go float64"},
+	})
+}
+
+func TestStdColorDirective(t *testing.T) {
+	testStdlib(t, []stdlibTest{
+		{"#rgb", `{"$std.color": "#f80"}`, jwalk.RGBA{R: 0xff, G: 0x88, B: 0x00, A: 0xff}, ""},
+		{"#rgba", `{"$std.color": "#f808"}`, jwalk.RGBA{R: 0xff, G: 0x88, B: 0x00, A: 0x88}, ""},
+		{"#rrggbb", `{"$std.color": "#Ff8001"}`, jwalk.RGBA{R: 0xff, G: 0x80, B: 0x01, A: 0xff}, ""},
+		{"#rrggbbaa", `{"$std.color": "#ff800100"}`, jwalk.RGBA{R: 0xff, G: 0x80, B: 0x01}, ""},
+		{"object", `{"$std.color": {"r": 255, "g": 136, "b": 0, "a": 128}}`, jwalk.RGBA{R: 255, G: 136, A: 128}, ""},
+		{"object/opaque", `{"$std.color": {"g": 1}}`, jwalk.RGBA{G: 1, A: 255}, ""},
+		{"missing #", `{"$std.color": "ff8800"}`, nil, `invalid hex color "ff8800": missing #`},
+		{"length", `{"$std.color": "#ff880"}`, nil, `invalid hex color "#ff880": expected 3, 4, 6 or 8 digits`},
+		{"digit", `{"$std.color": "#ff88g0"}`, nil, `invalid hex color "#ff88g0": bad digit 'g'`},
+		{"out of range", `{"$std.color": {"r": 256}}`, nil, "color component r out of range [0, 255]: 256"},
+		{"negative", `{"$std.color": {"a": -1}}`, nil, "color component a out of range [0, 255]: -1"},
+	})
+}
+
+func TestStdByteSizeDirective(t *testing.T) {
+	testStdlib(t, []stdlibTest{
+		{"bytes", `{"$std.bytesize": "512"}`, int64(512), ""},
+		{"B", `{"$std.bytesize": "512B"}`, int64(512), ""},
+		{"SI", `{"$std.bytesize": "10MB"}`, int64(10_000_000), ""},
+		{"kB", `{"$std.bytesize": "2kB"}`, int64(2000), ""},
+		{"IEC", `{"$std.bytesize": "10MiB"}`, int64(10 << 20), ""},
+		{"fraction", `{"$std.bytesize": "1.5 GiB"}`, int64(3 << 29), ""},
+		{"rounded", `{"$std.bytesize": "0.0001KiB"}`, int64(0), ""},
+		{"space", `{"$std.bytesize": " 1 TB "}`, int64(1e12), ""},
+		{"EiB", `{"$std.bytesize": "7EiB"}`, int64(7 << 60), ""},
+		{"short name", `{"$bytesize": "1KiB"}`, int64(1024), ""},
+		{"missing number", `{"$std.bytesize": "MiB"}`, nil, `invalid byte size "MiB": missing number`},
+		{"unknown unit", `{"$std.bytesize": "10mb"}`, nil, `invalid byte size "10mb": unknown unit "mb"`},
+		{"negative", `{"$std.bytesize": "-1KB"}`, nil, `invalid byte size "-1KB": missing number`},
+		{"bad number", `{"$std.bytesize": "1.2.3MB"}`, nil, `invalid byte size "1.2.3MB": strconv.ParseFloat`},
+		{"overflow", `{"$std.bytesize": "8EiB"}`, nil, `invalid byte size "8EiB": overflows int64`},
+		{"not a string", `{"$std.bytesize": 512}`, nil, "cannot unmarshal JSON number into Go string"},
+	})
+}
+
+func TestStdIPDirectives(t *testing.T) {
+	testStdlib(t, []stdlibTest{
+		{"ipv4", `{"$std.ip": "192.168.1.1"}`, netip.MustParseAddr("192.168.1.1"), ""},
+		{"ipv6", `{"$std.ip": "fe80::1%eth0"}`, netip.MustParseAddr("fe80::1%eth0"), ""},
+		{"ipv4-mapped", `{"$std.ip": "::ffff:10.0.0.1"}`, netip.MustParseAddr("::ffff:10.0.0.1"), ""},
+		{"ip/invalid", `{"$std.ip": "256.0.0.1"}`, nil, `ParseAddr("256.0.0.1")`},
+		{"ip/prefix", `{"$std.ip": "10.0.0.0/8"}`, nil, `ParseAddr("10.0.0.0/8")`},
+		{"ip/not a string", `{"$std.ip": [10, 0, 0, 1]}`, nil, "cannot unmarshal JSON array into Go string"},
+		{"cidr", `{"$std.cidr": "10.0.0.0/8"}`, netip.MustParsePrefix("10.0.0.0/8"), ""},
+		{"cidr/ipv6", `{"$std.cidr": "2001:db8::/32"}`, netip.MustParsePrefix("2001:db8::/32"), ""},
+		{"cidr/host bits kept", `{"$std.cidr": "10.1.2.3/8"}`, netip.MustParsePrefix("10.1.2.3/8"), ""},
+		{"cidr/no bits", `{"$std.cidr": "10.0.0.0"}`, nil, `netip.ParsePrefix("10.0.0.0"): no '/'`},
+		{"cidr/bits out of range", `{"$std.cidr": "10.0.0.0/33"}`, nil, `netip.ParsePrefix("10.0.0.0/33")`},
+	})
+}
@@ -0,0 +1,94 @@
+package jwalk
+
+import (
+	"errors"
+	"io"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// StreamEncoder writes a top-level JSON array element by element, so large
+// outputs can be produced with constant memory. Each element is encoded with
+// the Registry's marshalers, preserving Document order.
+//
+// Example:
+//
+//	se := jwalk.NewStreamEncoder(w, reg)
+//	if err := se.Open(); err != nil { ... }
+//	for _, rec := range records {
+//	    if err := se.WriteElement(rec); err != nil { ... }
+//	}
+//	if err := se.Close(); err != nil { ... }
+type StreamEncoder struct {
+	enc    *jsontext.Encoder
+	opened bool
+	closed bool
+}
+
+// NewStreamEncoder returns a StreamEncoder writing to w. Additional options
+// (e.g. jsontext.WithIndent) are applied to every element.
+func NewStreamEncoder(w io.Writer, reg *Registry, opts ...json.Options) *StreamEncoder {
+	opts = append([]json.Options{json.WithMarshalers(Marshalers(reg))}, opts...)
+	return &StreamEncoder{enc: jsontext.NewEncoder(w, opts...)}
+}
+
+// Open writes the opening bracket of the array. It must be called once,
+// before any WriteElement.
+func (se *StreamEncoder) Open() error {
+	if se.opened {
+		return errors.New("stream encoder already opened")
+	}
+	se.opened = true
+	return se.enc.WriteToken(jsontext.BeginArray)
+}
+
+// WriteElement encodes v as the next array element.
+func (se *StreamEncoder) WriteElement(v any) error {
+	if !se.opened || se.closed {
+		return errors.New("stream encoder not open")
+	}
+	return json.MarshalEncode(se.enc, v)
+}
+
+// Close writes the closing bracket of the array, completing the output. It
+// does not close the underlying writer.
+func (se *StreamEncoder) Close() error {
+	if !se.opened || se.closed {
+		return errors.New("stream encoder not open")
+	}
+	se.closed = true
+	return se.enc.WriteToken(jsontext.EndArray)
+}
+
+// DecodeStream decodes a sequence of top-level JSON values read from rd,
+// calling fn with each in turn, until rd is exhausted. Values may be separated
+// by any whitespace or, where unambiguous, by nothing at all (concatenated
+// JSON, e.g. {"a":1}{"a":2}), so it also accepts newline-delimited JSON.
+//
+// Each value is decoded as by Registry.DecodeValue: objects and arrays become
+// Documents and Arrays, and a root directive sentinel is dispatched. Decoding
+// stops at the first error, from the input or returned by fn. Under
+// WithBOMStripping, the input may start with a byte order mark.
+func DecodeStream(rd io.Reader, reg *Registry, fn func(any) error) error {
+	rd, err := reg.inputReader(rd)
+	if err != nil {
+		return err
+	}
+	dec := jsontext.NewDecoder(rd, reg.Options()...)
+	for {
+		if dec.PeekKind() == 0 { // end of input, or an error
+			if _, err := dec.ReadToken(); err != io.EOF {
+				return err
+			}
+			return nil
+		}
+		var v any
+		if err := json.UnmarshalDecode(dec, &v); err != nil {
+			return nestedError(err)
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
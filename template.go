@@ -0,0 +1,35 @@
+package jwalk
+
+import (
+	"text/template"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// TemplateDirective is a Directive registered as "template" that decodes
+// values of the form:
+//
+//	{"$template": "Hello {{.Name}}"}
+//
+// into a parsed *template.Template (text/template), which consumers execute
+// later with runtime data. Parse errors are reported at decode time. It is not
+// part of the standard set and must be registered explicitly.
+var TemplateDirective = NewTemplateDirective("template", nil)
+
+// NewTemplateDirective constructs a template Directive registered under the
+// given name. See TemplateDirective. funcs, if non-nil, is added to each
+// template's function map before parsing, so templates may call them.
+func NewTemplateDirective(name string, funcs template.FuncMap) *Directive {
+	return NewDirective(name, func(dec *jsontext.Decoder) (*template.Template, error) {
+		var text string
+		if err := json.UnmarshalDecode(dec, &text); err != nil {
+			return nil, err
+		}
+		t := template.New(name)
+		if funcs != nil {
+			t = t.Funcs(funcs)
+		}
+		return t.Parse(text)
+	})
+}
@@ -0,0 +1,87 @@
+package jwalk
+
+import (
+	"fmt"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// TypedDocument is an ordered JSON object whose values all have type T, the
+// homogeneous counterpart of Document (which, not being generic, cannot be
+// parameterized itself). Decoding a value that does not fit T is an error:
+//
+//	var labels jwalk.TypedDocument[string]
+//	err := json.Unmarshal([]byte(`{"b": "x", "a": "y"}`), &labels) // keeps b before a
+//
+// It decodes and encodes through its own methods, so no Registry is needed;
+// values are decoded with the options in effect, so a TypedDocument[any] or
+// TypedDocument[Document] decoded with a Registry's options gets its ordered
+// handling and directives. JSON null decodes as a nil TypedDocument, and a nil
+// TypedDocument encodes as null.
+type TypedDocument[T any] []TypedEntry[T]
+
+// TypedEntry is a single entry in a TypedDocument.
+type TypedEntry[T any] struct {
+	Key   string
+	Value T
+}
+
+// UnmarshalJSONFrom implements json.UnmarshalerFrom, decoding a JSON object
+// in key order.
+func (d *TypedDocument[T]) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	switch k := dec.PeekKind(); k {
+	case 'n':
+		if _, err := dec.ReadToken(); err != nil {
+			return err
+		}
+		*d = nil
+		return nil
+	case '{':
+	default:
+		return fmt.Errorf("expected object, got %s", kindName(k))
+	}
+
+	if _, err := dec.ReadToken(); err != nil { // '{'
+		return fmt.Errorf("read object open: %w", err)
+	}
+	out := TypedDocument[T]{}
+	for dec.PeekKind() != '}' {
+		var e TypedEntry[T]
+		if err := json.UnmarshalDecode(dec, &e.Key); err != nil {
+			return fmt.Errorf("read object key: %w", err)
+		}
+		if err := json.UnmarshalDecode(dec, &e.Value); err != nil {
+			return fmt.Errorf("read object value for key %q: %w", e.Key, err)
+		}
+		out = append(out, e)
+	}
+	if _, err := dec.ReadToken(); err != nil { // '}'
+		return fmt.Errorf("read object close: %w", err)
+	}
+	*d = out
+	return nil
+}
+
+// MarshalJSONTo implements json.MarshalerTo, encoding d as a JSON object with
+// entries in slice order.
+func (d TypedDocument[T]) MarshalJSONTo(enc *jsontext.Encoder) error {
+	if d == nil {
+		return enc.WriteToken(jsontext.Null)
+	}
+	if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+		return fmt.Errorf("write object open: %w", err)
+	}
+	for _, e := range d {
+		if err := enc.WriteToken(jsontext.String(e.Key)); err != nil {
+			return fmt.Errorf("write object key %q: %w", e.Key, err)
+		}
+		if err := json.MarshalEncode(enc, e.Value); err != nil {
+			return fmt.Errorf("write object value for key %q: %w", e.Key, err)
+		}
+	}
+	if err := enc.WriteToken(jsontext.EndObject); err != nil {
+		return fmt.Errorf("write object close: %w", err)
+	}
+	return nil
+}
@@ -1,7 +1,12 @@
 package jwalk
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/go-json-experiment/json"
 	"github.com/go-json-experiment/json/jsontext"
@@ -13,47 +18,162 @@ import (
 //     dispatched through registered directives
 //   - *Document: ordered object decoding
 //   - *Array: ordered array decoding
+//   - *[]any: as *Array, so that a SpliceResult is spliced into it
+//   - *[]Document: arrays of records, each element an ordered Document
+//   - *map[string]any: rejected for objects under WithRejectUnorderedMaps
+//
+// The unmarshalers apply wherever the target type appears, not just at the
+// root. A struct field typed Document, *Document, Array or any preserves order
+// while its sibling fields are decoded by the json package as usual:
+//
+//	type Config struct {
+//	    Name string         `json:"name"`
+//	    Meta jwalk.Document `json:"meta"` // ordered
+//	}
 func Unmarshalers(reg *Registry) *json.Unmarshalers {
+	return reg.unmarshalers(nil)
+}
+
+// unmarshalers returns jwalk's unmarshalers, bound to the decode state st if
+// it is not nil (see decodeFrom).
+func (r *Registry) unmarshalers(st *decodeState) *json.Unmarshalers {
 	return json.JoinUnmarshalers(
-		unmarshalValue(reg), // *any (objects, arrays, directives)
-		unmarshalDocument(),
-		unmarshalCollection(),
+		chargeValues(st),
+		unmarshalValue(r, st), // *any (objects, arrays, directives)
+		unmarshalDocument(r, st),
+		unmarshalCollection(r, st),
+		unmarshalSlice(r, st),
+		unmarshalDocuments(r, st),
+		unmarshalStringMap(r),
 	)
 }
 
+// chargeValues returns an unmarshaler that charges every value against the
+// WithMaxTotalValues budget of st before passing it on, so that values the
+// json package decodes itself, such as the elements of a []any or the fields
+// of a struct, count too. It returns nil, which JoinUnmarshalers ignores, if
+// there is no budget or st is nil.
+func chargeValues(st *decodeState) *json.Unmarshalers {
+	if st == nil || st.reg.maxTotalValues <= 0 {
+		return nil
+	}
+	return json.UnmarshalFromFunc(func(dec *jsontext.Decoder, _ any) error {
+		if k, n := dec.StackIndex(dec.StackDepth()); k == '{' && n%2 == 0 {
+			return json.SkipFunc // a map key
+		}
+		if err := st.charge(dec); err != nil {
+			return err
+		}
+		return json.SkipFunc
+	})
+}
+
+// Combine joins reg's unmarshalers with the caller's own, for decoding with
+// both at once; json.WithUnmarshalers keeps only the last set it is given, so
+// the two cannot simply be passed as separate options:
+//
+//	err := json.Unmarshal(data, &cfg, json.WithUnmarshalers(jwalk.Combine(reg, domainUnmarshalers)))
+//
+// The extra unmarshalers take precedence, in order, over jwalk's: the first
+// unmarshaler for a type decodes it, and one returning json.SkipFunc passes the
+// value on to the next. So a caller's unmarshaler for map[string]any replaces
+// jwalk's (which only rejects maps under WithRejectUnorderedMaps), and an extra
+// unmarshaler for any, Document or Array replaces jwalk's handling of that
+// type, including directive dispatch, unless it skips the value. Nil entries
+// in extra are ignored.
+func Combine(reg *Registry, extra ...*json.Unmarshalers) *json.Unmarshalers {
+	return json.JoinUnmarshalers(append(slices.Clip(extra), Unmarshalers(reg))...)
+}
+
+// DecodeObject decodes the JSON object at dec's current position into an
+// ordered Document using reg, for callers driving their own jsontext.Decoder
+// who want jwalk's object handling for one value. Nested values are decoded
+// with reg's unmarshalers whether or not dec was created with them. As with
+// Unmarshal into *Document, a sentinel at the top level of the object is kept
+// as plain data; nested sentinels are dispatched.
+//
+// Any value other than an object, including null, is an error.
+func DecodeObject(dec *jsontext.Decoder, reg *Registry) (Document, error) {
+	if k := dec.PeekKind(); k != '{' {
+		return nil, fmt.Errorf("expected object, got %s", kindName(k))
+	}
+	var d Document
+	if err := json.UnmarshalDecode(dec, &d, reg.Options()...); err != nil {
+		return nil, nestedError(err)
+	}
+	return d, nil
+}
+
+// DecodeArray decodes the JSON array at dec's current position into an Array
+// using reg. See DecodeObject. Any value other than an array, including null,
+// is an error.
+func DecodeArray(dec *jsontext.Decoder, reg *Registry) (Array, error) {
+	if k := dec.PeekKind(); k != '[' {
+		return nil, fmt.Errorf("expected array, got %s", kindName(k))
+	}
+	var a Array
+	if err := json.UnmarshalDecode(dec, &a, reg.Options()...); err != nil {
+		return nil, nestedError(err)
+	}
+	return a, nil
+}
+
 // unmarshalValue returns an unmarshaler for *any. It:
 //
 //   - Wraps JSON objects as Document instead of map[string]any
-//   - Wraps JSON arrays as Array - Detects sentinel objects {"$<name>": <value>[, ...]}
-//     and invokes the corresponding directive if registered
+//   - Wraps JSON arrays as Array ([]any under WithPlainArrays)
+//   - Detects sentinel objects {"$<name>": <value>[, ...]} and invokes the
+//     corresponding directive if registered, splicing a SpliceResult into an
+//     enclosing array
 //   - Leaves primitive values (string, number, bool, null) to other unmarshalers
 //
 // Empty objects decode as an empty Document, and empty arrays as an empty
 // Array.
-func unmarshalValue(reg *Registry) *json.Unmarshalers {
+func unmarshalValue(reg *Registry, bound *decodeState) *json.Unmarshalers {
 	return json.UnmarshalFromFunc(func(dec *jsontext.Decoder, v *any) error {
-		switch dec.PeekKind() {
+		kind := dec.PeekKind()
+		if reg.requireObjectRoot && kind != '{' && dec.StackDepth() == 0 {
+			// only the decode's own root, not that of a nested decoder
+			// started by a directive, e.g. for an included file
+			st, release := reg.acquireState(dec, bound)
+			release()
+			if st.root == dec {
+				return fmt.Errorf("root must be a JSON object, found %s", kindName(kind))
+			}
+		}
+
+		switch kind {
 		case '{':
+			st, release := reg.acquireState(dec, bound)
+			defer release()
+
 			// object (possibly a directive sentinel)
-			val, wasDirective, err := unmarshalObject(dec, reg, true)
+			val, wasDirective, err := unmarshalObject(dec, st, true)
 			if err != nil {
 				return err
 			}
 
 			if wasDirective {
-				*v = val
+				*v = unsplice(dec, reg, val)
 			} else {
 				*v = val.(Document)
 			}
 			return nil
 
 		case '[':
+			st, release := reg.acquireState(dec, bound)
+			defer release()
+
 			// array
-			arr, err := unmarshalArray(dec, reg)
+			arr, err := unmarshalArray(dec, st)
 			if err != nil {
 				return err
 			}
-			*v = arr
+			if reg.plainArrays {
+				*v = []any(arr)
+			} else {
+				*v = arr
+			}
 			return nil
 
 		default:
@@ -67,13 +187,16 @@ func unmarshalValue(reg *Registry) *json.Unmarshalers {
 // Directive sentinel objects are not interpreted here; that only when decoding
 // into interface{} via unmarshalValue. This allows callers to opt in to
 // directive semantics selectively.
-func unmarshalDocument() *json.Unmarshalers {
+func unmarshalDocument(reg *Registry, bound *decodeState) *json.Unmarshalers {
 	return json.UnmarshalFromFunc(func(dec *jsontext.Decoder, v *Document) error {
 		if dec.PeekKind() != '{' {
 			return json.SkipFunc
 		}
 
-		val, _, err := unmarshalObject(dec, nil, false)
+		st, release := reg.acquireState(dec, bound)
+		defer release()
+
+		val, _, err := unmarshalObject(dec, st, false)
 		if err != nil {
 			return err
 		}
@@ -84,13 +207,16 @@ func unmarshalDocument() *json.Unmarshalers {
 }
 
 // unmarshalCollection decodes a JSON array into *Array.
-func unmarshalCollection() *json.Unmarshalers {
+func unmarshalCollection(reg *Registry, bound *decodeState) *json.Unmarshalers {
 	return json.UnmarshalFromFunc(func(dec *jsontext.Decoder, v *Array) error {
 		if dec.PeekKind() != '[' {
 			return json.SkipFunc
 		}
 
-		arr, err := unmarshalArray(dec, nil)
+		st, release := reg.acquireState(dec, bound)
+		defer release()
+
+		arr, err := unmarshalArray(dec, st)
 		if err != nil {
 			return err
 		}
@@ -100,15 +226,101 @@ func unmarshalCollection() *json.Unmarshalers {
 	})
 }
 
+// unmarshalSlice decodes a JSON array into *[]any as unmarshalCollection
+// does, rather than leaving it to the json package, which would leave a
+// SpliceResult element unspliced.
+func unmarshalSlice(reg *Registry, bound *decodeState) *json.Unmarshalers {
+	return json.UnmarshalFromFunc(func(dec *jsontext.Decoder, v *[]any) error {
+		if dec.PeekKind() != '[' {
+			return json.SkipFunc
+		}
+
+		st, release := reg.acquireState(dec, bound)
+		defer release()
+
+		arr, err := unmarshalArray(dec, st)
+		if err != nil {
+			return err
+		}
+
+		*v = []any(arr)
+		return nil
+	})
+}
+
+// unmarshalDocuments decodes a JSON array of objects into *[]Document, one
+// ordered Document per element. Any element that is not an object (including
+// null) is an error. As with *Document, directive sentinels are not
+// interpreted at the element level.
+func unmarshalDocuments(reg *Registry, bound *decodeState) *json.Unmarshalers {
+	return json.UnmarshalFromFunc(func(dec *jsontext.Decoder, v *[]Document) error {
+		if dec.PeekKind() != '[' {
+			return json.SkipFunc
+		}
+
+		st, release := reg.acquireState(dec, bound)
+		defer release()
+
+		if err := st.charge(dec); err != nil {
+			return err
+		}
+		if _, err := dec.ReadToken(); err != nil { // '['
+			return fmt.Errorf("read array open: %w", err)
+		}
+		st.countContainer(dec, '[')
+
+		docs := make([]Document, 0, reg.arrCap)
+		for i := 0; dec.PeekKind() != ']'; i++ {
+			if k := dec.PeekKind(); k != '{' {
+				return fmt.Errorf("array element %d is %s, not an object", i, kindName(k))
+			}
+			val, _, err := unmarshalObject(dec, st, false)
+			if err != nil {
+				return nestedError(fmt.Errorf("read array element %d: %w", i, err))
+			}
+			docs = append(docs, val.(Document))
+		}
+
+		if _, err := dec.ReadToken(); err != nil { // ']'
+			return fmt.Errorf("read array close: %w", err)
+		}
+
+		*v = docs
+		return nil
+	})
+}
+
+// unmarshalStringMap guards map[string]any targets, which cannot preserve key
+// order, when the Registry has WithRejectUnorderedMaps set.
+func unmarshalStringMap(reg *Registry) *json.Unmarshalers {
+	return json.UnmarshalFromFunc(func(dec *jsontext.Decoder, v *map[string]any) error {
+		if !reg.rejectUnordered || dec.PeekKind() != '{' {
+			return json.SkipFunc
+		}
+		return errors.New("map[string]any cannot preserve key order; use jwalk.Document")
+	})
+}
+
 // unmarshalObject decodes a JSON object. It returns:
 //
 //   - (val, true, nil) if allowDirective is true, the first key starts with "$", and
 //     the registry successfully dispatches the directive.
 //   - (Document, false, nil) otherwise, preserving key order.
-func unmarshalObject(dec *jsontext.Decoder, reg *Registry, allowDirective bool) (val any, wasDirective bool, err error) {
+//
+// If a value-mode directive fails, the rest of the sentinel object is consumed
+// before returning the error, so dec is left after the object, as it would be
+// after success, and a caller driving dec itself may carry on. Other errors,
+// and syntax errors in particular, may leave dec anywhere within the object.
+func unmarshalObject(dec *jsontext.Decoder, st *decodeState, allowDirective bool) (val any, wasDirective bool, err error) {
+	if err = st.charge(dec); err != nil {
+		return nil, false, err
+	}
+	objOff := dec.InputOffset()
 	if _, err = dec.ReadToken(); err != nil { // '{'
 		return nil, false, fmt.Errorf("read object open: %w", err)
 	}
+	st.countContainer(dec, '{')
+	objDepth := dec.StackDepth()
 
 	if dec.PeekKind() == '}' { // empty
 		if _, err = dec.ReadToken(); err != nil { // '}'
@@ -117,19 +329,48 @@ func unmarshalObject(dec *jsontext.Decoder, reg *Registry, allowDirective bool)
 		return Document{}, false, nil
 	}
 
+	allowDirective = allowDirective && !st.reg.directivesDisabled
+
 	// read first key
-	var firstKey string
-	if err = json.UnmarshalDecode(dec, &firstKey); err != nil {
+	firstOff := dec.InputOffset()
+	firstKey, err := st.readKey(dec)
+	if err != nil {
 		return nil, false, fmt.Errorf("read object first key: %w", err)
 	}
 
-	if allowDirective && firstKey != "" && firstKey[0] == '$' {
+	if allowDirective && st.reg.sentinelScan {
+		return unmarshalScanned(dec, st, firstKey, objOff, firstOff)
+	}
+
+	var isSentinel bool
+	if allowDirective {
+		if isSentinel, err = st.sentinelKey(firstKey); err != nil {
+			return nil, false, directiveError(string(dec.StackPointer()), err)
+		}
+	}
+	if isSentinel {
+		keyPtr := string(dec.StackPointer())
+		if st.reg.directiveMode(firstKey[1:]) == ObjectMode {
+			return unmarshalWholeSentinel(dec, st, firstKey, parentPointer(keyPtr), objOff)
+		}
+
 		// Pass full sentinel (still accepted) so handler context includes it.
-		vv, err := reg.InvokeDirective(firstKey[1:], dec)
+		st.countDirective(firstKey[1:])
+		valOff := dec.InputOffset()
+		vv, err := st.reg.InvokeDirective(firstKey[1:], dec)
+		if err == nil && !valueConsumed(dec, objDepth) {
+			err = fmt.Errorf("directive %q did not consume exactly its value", firstKey[1:])
+		}
 		if err != nil {
+			drainObject(dec, objDepth)
 			// registry already provided context in error
+			err = directiveError(keyPtr, err)
+			if dec.StackDepth() < objDepth && st.collect(err) {
+				return nil, true, nil
+			}
 			return nil, false, err
 		}
+		valEnd := dec.InputOffset()
 
 		// skip any extra fields after the directive root field
 		for dec.PeekKind() != '}' {
@@ -140,27 +381,46 @@ func unmarshalObject(dec *jsontext.Decoder, reg *Registry, allowDirective bool)
 		if _, err = dec.ReadToken(); err != nil {
 			return nil, false, fmt.Errorf("directive %q read object close: %w", firstKey, err)
 		}
+		st.recordSource(dec, parentPointer(keyPtr), firstKey[1:], objOff, valOff, valEnd)
 
 		return vv, true, nil
 	}
 
 	// regular object path
-	var firstVal any
-	if err = json.UnmarshalDecode(dec, &firstVal); err != nil {
-		return nil, false, fmt.Errorf("read object value for key %q: %w", firstKey, err)
+	st.recordPosition(dec, firstOff)
+	if err = st.chargeChild(dec); err != nil {
+		return nil, false, err
+	}
+	kind := dec.PeekKind()
+	firstVal, err := st.readValue(dec, kind)
+	if err != nil {
+		return nil, false, nestedError(fmt.Errorf("read object value for key %q: %w", firstKey, err))
+	}
+	if firstVal, err = st.transform(dec, kind, firstVal); err != nil {
+		return nil, false, err
 	}
 
-	res := Document{{Key: firstKey, Value: firstVal}}
+	res := make(Document, 1, max(st.reg.objCap, 1))
+	res[0] = Entry{Key: firstKey, Value: firstVal}
 
 	for dec.PeekKind() != '}' {
-		var k string
-		if err = json.UnmarshalDecode(dec, &k); err != nil {
+		off := dec.InputOffset()
+		k, err := st.readKey(dec)
+		if err != nil {
 			return nil, false, fmt.Errorf("read object key: %w", err)
 		}
+		st.recordPosition(dec, off)
+		if err = st.chargeChild(dec); err != nil {
+			return nil, false, err
+		}
 
-		var vv any
-		if err = json.UnmarshalDecode(dec, &vv); err != nil {
-			return nil, false, fmt.Errorf("read object value: %w", err)
+		kind := dec.PeekKind()
+		vv, err := st.readValue(dec, kind)
+		if err != nil {
+			return nil, false, nestedError(fmt.Errorf("read object value: %w", err))
+		}
+		if vv, err = st.transform(dec, kind, vv); err != nil {
+			return nil, false, err
 		}
 
 		res = append(res, Entry{Key: k, Value: vv})
@@ -173,11 +433,270 @@ func unmarshalObject(dec *jsontext.Decoder, reg *Registry, allowDirective bool)
 	return res, false, nil
 }
 
+// valueConsumed reports whether dec is positioned just after a complete member
+// value of the object at stack depth depth.
+func valueConsumed(dec *jsontext.Decoder, depth int) bool {
+	if dec.StackDepth() != depth {
+		return false
+	}
+	_, n := dec.StackIndex(depth) // names and values are counted separately
+	return n%2 == 0
+}
+
+// drainObject consumes what is left of the object at stack depth depth after a
+// directive stopped somewhere within it, up to and including its closing
+// brace. It gives up, leaving dec where it failed, on a syntax error or if
+// the object has already been closed.
+func drainObject(dec *jsontext.Decoder, depth int) {
+	for dec.StackDepth() > depth { // finish any value left open
+		if _, err := dec.ReadToken(); err != nil {
+			return
+		}
+	}
+	if dec.StackDepth() < depth {
+		return
+	}
+	for dec.PeekKind() != '}' {
+		if err := dec.SkipValue(); err != nil {
+			return
+		}
+	}
+	_, _ = dec.ReadToken() // '}'
+}
+
+// unmarshalWholeSentinel dispatches an ObjectMode directive for the object at
+// objPtr whose opening brace and first key have already been read. The object
+// is buffered and re-assembled so the directive sees it from its opening
+// brace. objOff is the input offset before the opening brace.
+func unmarshalWholeSentinel(dec *jsontext.Decoder, st *decodeState, key, objPtr string, objOff int64) (val any, wasDirective bool, err error) {
+	var buf bytes.Buffer
+	enc := jsontext.NewEncoder(&buf)
+	if err = enc.WriteToken(jsontext.BeginObject); err != nil {
+		return nil, false, err
+	}
+	if err = enc.WriteToken(jsontext.String(key)); err != nil {
+		return nil, false, err
+	}
+	for {
+		raw, err := dec.ReadValue()
+		if err != nil {
+			return nil, false, fmt.Errorf("directive %q read object value: %w", key, err)
+		}
+		if err = enc.WriteValue(raw); err != nil {
+			return nil, false, err
+		}
+		if dec.PeekKind() == '}' {
+			break
+		}
+		name, err := dec.ReadToken()
+		if err != nil {
+			return nil, false, fmt.Errorf("directive %q read object key: %w", key, err)
+		}
+		if err = enc.WriteToken(name); err != nil {
+			return nil, false, err
+		}
+	}
+	if _, err = dec.ReadToken(); err != nil { // '}'
+		return nil, false, fmt.Errorf("directive %q read object close: %w", key, err)
+	}
+	if err = enc.WriteToken(jsontext.EndObject); err != nil {
+		return nil, false, err
+	}
+
+	v, err := st.invokeRaw(dec, key[1:], bytes.TrimSpace(buf.Bytes()), objPtr)
+	if err != nil {
+		err = rawDirectiveError(objPtr, objPtr, err)
+		if st.collect(err) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	st.recordSource(dec, objPtr, key[1:], objOff, objOff, dec.InputOffset())
+	return v, true, nil
+}
+
+// sentinel is a buffered "$"-prefixed object member.
+type sentinel struct {
+	key        string
+	raw        jsontext.Value
+	start, end int64 // input offsets around raw
+}
+
+// unmarshalScanned decodes the remainder of an object whose first key has
+// already been read, treating a "$"-prefixed key at any position as a
+// directive sentinel (WithSentinelScan). objOff and off are the input offsets
+// before the opening brace and the first key.
+//
+// Sentinel values are buffered raw until the whole object has been read, since
+// which directive to dispatch may depend on keys that appear later. Other
+// members are decoded as they are read.
+func unmarshalScanned(dec *jsontext.Decoder, st *decodeState, key string, objOff, off int64) (val any, wasDirective bool, err error) {
+	objPtr := parentPointer(string(dec.StackPointer()))
+	res := make(Document, 0, st.reg.objCap)
+	var sentinels []sentinel
+	var isSentinel []bool // member order, for ObjectMode directives
+	for {
+		sent, err := st.sentinelKey(key)
+		if err != nil {
+			return nil, false, directiveError(string(dec.StackPointer()), err)
+		}
+		isSentinel = append(isSentinel, sent)
+		if sent {
+			start := dec.InputOffset()
+			raw, err := dec.ReadValue()
+			if err != nil {
+				return nil, false, fmt.Errorf("directive %q read value: %w", key, err)
+			}
+			sentinels = append(sentinels, sentinel{key: key, raw: raw.Clone(), start: start, end: dec.InputOffset()})
+		} else {
+			st.recordPosition(dec, off)
+			if err = st.chargeChild(dec); err != nil {
+				return nil, false, err
+			}
+			kind := dec.PeekKind()
+			v, err := st.readValue(dec, kind)
+			if err != nil {
+				return nil, false, nestedError(fmt.Errorf("read object value for key %q: %w", key, err))
+			}
+			if v, err = st.transform(dec, kind, v); err != nil {
+				return nil, false, err
+			}
+			res = append(res, Entry{Key: key, Value: v})
+		}
+
+		if dec.PeekKind() == '}' {
+			break
+		}
+		off = dec.InputOffset()
+		if key, err = st.readKey(dec); err != nil {
+			return nil, false, fmt.Errorf("read object key: %w", err)
+		}
+	}
+
+	if _, err = dec.ReadToken(); err != nil { // '}'
+		return nil, false, fmt.Errorf("read object close: %w", err)
+	}
+
+	if len(sentinels) == 0 {
+		return res, false, nil
+	}
+
+	// The members decoded before (or after) the sentinel are discarded, so
+	// forget any positions recorded for them.
+	st.dropPositions(string(dec.StackPointer()))
+
+	s, err := st.reg.pickSentinel(sentinels)
+	if err != nil {
+		return nil, false, err
+	}
+	if st.reg.directiveMode(s.key[1:]) == ObjectMode {
+		obj, err := rebuildObject(st.reg, res, sentinels, isSentinel)
+		if err != nil {
+			return nil, false, fmt.Errorf("directive %q rebuild object: %w", s.key, err)
+		}
+		v, err := st.invokeRaw(dec, s.key[1:], obj, objPtr)
+		if err != nil {
+			err = rawDirectiveError(objPtr, objPtr, err)
+			if st.collect(err) {
+				return nil, true, nil
+			}
+			return nil, false, err
+		}
+		st.recordSource(dec, objPtr, s.key[1:], objOff, objOff, dec.InputOffset())
+		return v, true, nil
+	}
+
+	base := objPtr + "/" + escapePointerToken(s.key)
+	v, err := st.invokeRaw(dec, s.key[1:], s.raw, base)
+	if err != nil {
+		// the directive ran on its own decoder, so nested paths are relative
+		// to the sentinel value
+		err = rawDirectiveError(base, objPtr, err)
+		if st.collect(err) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	st.recordSource(dec, objPtr, s.key[1:], objOff, s.start, s.end)
+	return v, true, nil
+}
+
+// rebuildObject re-assembles a scanned object for an ObjectMode directive,
+// with members in their original order. Sentinel members keep their source
+// text; the others, already decoded, are re-encoded from their values.
+func rebuildObject(reg *Registry, res Document, sentinels []sentinel, isSentinel []bool) (jsontext.Value, error) {
+	var buf bytes.Buffer
+	enc := jsontext.NewEncoder(&buf, json.WithMarshalers(Marshalers(reg)))
+	if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+		return nil, err
+	}
+	var si, ri int
+	for _, sent := range isSentinel {
+		if sent {
+			s := sentinels[si]
+			si++
+			if err := enc.WriteToken(jsontext.String(s.key)); err != nil {
+				return nil, err
+			}
+			if err := enc.WriteValue(s.raw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		e := res[ri]
+		ri++
+		if err := enc.WriteToken(jsontext.String(e.Key)); err != nil {
+			return nil, err
+		}
+		if err := json.MarshalEncode(enc, e.Value); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.WriteToken(jsontext.EndObject); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// directiveMode returns the mode of the named directive, or ValueMode if it
+// does not resolve (InvokeDirective then reports why).
+func (r *Registry) directiveMode(name string) DirectiveMode {
+	d, err := r.snap.Load().lookup(name, r.sepByte)
+	if err != nil {
+		return ValueMode
+	}
+	return d.mode
+}
+
+// pickSentinel chooses which of an object's sentinels to dispatch.
+func (r *Registry) pickSentinel(cands []sentinel) (sentinel, error) {
+	if len(cands) == 1 {
+		return cands[0], nil
+	}
+	for _, name := range r.sentinelPriority {
+		for _, c := range cands {
+			if c.key[1:] == name {
+				return c, nil
+			}
+		}
+	}
+
+	keys := make([]string, len(cands))
+	for i, c := range cands {
+		keys[i] = c.key
+	}
+	return sentinel{}, fmt.Errorf("multiple directive keys (%s) with no priority between them", strings.Join(keys, ", "))
+}
+
 // unmarshalArray decodes a JSON array into Array.
-func unmarshalArray(dec *jsontext.Decoder, _ *Registry) (Array, error) {
+func unmarshalArray(dec *jsontext.Decoder, st *decodeState) (Array, error) {
+	if err := st.charge(dec); err != nil {
+		return nil, err
+	}
 	if _, err := dec.ReadToken(); err != nil { // '['
 		return nil, fmt.Errorf("read array open: %w", err)
 	}
+	st.countContainer(dec, '[')
 
 	if dec.PeekKind() == ']' { // empty
 		if _, err := dec.ReadToken(); err != nil {
@@ -186,12 +705,24 @@ func unmarshalArray(dec *jsontext.Decoder, _ *Registry) (Array, error) {
 		return Array{}, nil
 	}
 
-	arr := make(Array, 0)
+	arr := make(Array, 0, st.reg.arrCap)
 
 	for dec.PeekKind() != ']' {
-		var elem any
-		if err := json.UnmarshalDecode(dec, &elem); err != nil {
-			return nil, fmt.Errorf("read array element: %w", err)
+		if err := st.chargeChild(dec); err != nil {
+			return nil, err
+		}
+		kind := dec.PeekKind()
+		elem, err := st.readValue(dec, kind)
+		if err != nil {
+			return nil, nestedError(fmt.Errorf("read array element: %w", err))
+		}
+		elem, err = st.transform(dec, kind, elem)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := elem.(SpliceResult); ok {
+			arr = append(arr, s...)
+			continue
 		}
 		arr = append(arr, elem)
 	}
@@ -202,3 +733,59 @@ func unmarshalArray(dec *jsontext.Decoder, _ *Registry) (Array, error) {
 
 	return arr, nil
 }
+
+// readValue decodes the next value in dec, of the given kind, as a member of
+// an object or array.
+//
+// If the Registry cannot dispatch directives, because it has none or they are
+// disabled, and dec decodes with jwalk's unmarshalers alone, no value can need
+// one, so primitives are converted straight from their token instead of going
+// through json.UnmarshalDecode, whose unmarshaler dispatch dominates the cost
+// of decoding directive-free input. Containers always go through it, as do
+// primitives when the caller's own unmarshalers are joined with jwalk's (see
+// Combine), since those may handle them.
+func (st *decodeState) readValue(dec *jsontext.Decoder, kind jsontext.Kind) (any, error) {
+	if kind == '{' || kind == '[' || st.reg.dispatchesDirectives() || !st.jwalkOnly(dec) {
+		var v any
+		err := json.UnmarshalDecode(dec, &v)
+		return v, err
+	}
+
+	tok, err := dec.ReadToken()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case 'n':
+		return nil, nil
+	case 't', 'f':
+		return tok.Bool(), nil
+	case '"':
+		return tok.String(), nil
+	}
+	f, err := strconv.ParseFloat(tok.String(), 64)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode number %s into float64: %w", tok.String(), errors.Unwrap(err))
+	}
+	return f, nil
+}
+
+// jwalkOnly reports whether dec decodes with no unmarshalers but jwalk's own.
+func (st *decodeState) jwalkOnly(dec *jsontext.Decoder) bool {
+	u, _ := json.GetOption(dec.Options(), json.WithUnmarshalers)
+	return u == st.reg.shared || u == st.bound && u != nil
+}
+
+// kindName returns a human-readable name for a JSON value kind.
+func kindName(k jsontext.Kind) string {
+	switch k {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "boolean"
+	default:
+		return k.String()
+	}
+}
@@ -0,0 +1,348 @@
+package jwalk_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+
+	"github.com/calumari/jwalk"
+)
+
+// numbersAsStrings is a caller's unmarshaler for any that decodes numbers as
+// "num:<text>" and passes everything else on.
+var numbersAsStrings = json.UnmarshalFromFunc(func(dec *jsontext.Decoder, v *any) error {
+	if dec.PeekKind() != '0' {
+		return json.SkipFunc
+	}
+	tok, err := dec.ReadToken()
+	if err != nil {
+		return err
+	}
+	*v = "num:" + tok.String()
+	return nil
+})
+
+func TestCombine(t *testing.T) {
+	const want = `{"a":"num:1","b":["num:2",{"c":"num:3"}],"d":"x"}`
+	for _, tt := range []struct {
+		name string
+		opts []jwalk.Option
+	}{
+		{"no directives", nil},
+		{"directives", []jwalk.Option{jwalk.WithStdlib()}},
+		{"directives disabled", []jwalk.Option{jwalk.WithStdlib(), jwalk.WithDirectivesDisabled()}},
+		{"stateful", []jwalk.Option{jwalk.WithMaxTotalValues(100)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := jwalk.NewRegistry(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in := []byte(`{"a":1,"b":[2,{"c":3}],"d":"x"}`)
+			u := json.WithUnmarshalers(jwalk.Combine(reg, nil, numbersAsStrings))
+
+			var v any
+			if err := json.Unmarshal(in, &v, u); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := reg.Marshal(v); err != nil || string(got) != want {
+				t.Errorf("json.Unmarshal: got %s (%v), want %s", got, err, want)
+			}
+
+			v = nil
+			if err := reg.Unmarshal(in, &v, u); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := reg.Marshal(v); err != nil || string(got) != want {
+				t.Errorf("Registry.Unmarshal: got %s (%v), want %s", got, err, want)
+			}
+		})
+	}
+}
+
+func TestCombineDirectives(t *testing.T) {
+	reg, err := jwalk.NewRegistry(jwalk.WithStdlib())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// objects still reach jwalk, so directives are still dispatched
+	var v any
+	in := `{"a": {"$std.duration": "1s"}, "b": 2}`
+	if err := json.Unmarshal([]byte(in), &v, json.WithUnmarshalers(jwalk.Combine(reg, numbersAsStrings))); err != nil {
+		t.Fatal(err)
+	}
+	d := v.(jwalk.Document)
+	if got := d[0].Value; got != time.Second {
+		t.Errorf("a = %v, want 1s", got)
+	}
+	if got := d[1].Value; got != "num:2" {
+		t.Errorf("b = %v, want num:2", got)
+	}
+}
+
+// BenchmarkUnmarshalPrimitives compares decoding directive-free input with a
+// Registry that cannot dispatch directives, whose primitives skip the json
+// package's unmarshaler dispatch, against one that can.
+func BenchmarkUnmarshalPrimitives(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := range 1000 {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`{"id":123,"name":"widget","price":9.99,"active":true,"tags":["a","b",null]}`)
+	}
+	sb.WriteString("]")
+	in := []byte(sb.String())
+
+	for _, bm := range []struct {
+		name string
+		opts []jwalk.Option
+	}{
+		{"NoDirectives", nil},
+		{"Directives", []jwalk.Option{jwalk.WithStdlib()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			reg, err := jwalk.NewRegistry(bm.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(in)))
+			b.ReportAllocs()
+			for b.Loop() {
+				var v any
+				if err := reg.Unmarshal(in, &v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRejectUnorderedMaps(t *testing.T) {
+	reg, err := jwalk.NewRegistry(jwalk.WithRejectUnorderedMaps())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		in      string
+		out     any
+		wantErr bool
+	}{
+		{"map[string]any", `{"a": 1}`, new(map[string]any), true},
+		{"map[string]any field", `{"M": {"a": 1}}`, new(struct{ M map[string]any }), true},
+		{"null map[string]any", `null`, new(map[string]any), false},
+		{"any", `{"a": {"b": 1}}`, new(any), false},
+		{"Document", `{"a": 1}`, new(jwalk.Document), false},
+		{"map[string]int", `{"a": 1}`, new(map[string]int), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reg.Unmarshal([]byte(tt.in), tt.out)
+			if gotErr := err != nil && strings.Contains(err.Error(), "cannot preserve key order"); gotErr != tt.wantErr {
+				t.Errorf("Unmarshal error = %v, want key order error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// partial is a directive that fails after reading part of its value: the
+// opening bracket and first element of an array.
+var partial = jwalk.NewDirective("partial", func(dec *jsontext.Decoder) (any, error) {
+	if _, err := dec.ReadToken(); err != nil {
+		return nil, err
+	}
+	if err := dec.SkipValue(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("partial failure")
+})
+
+// overread is a directive that reads past its value into the sentinel's next
+// member name.
+var overread = jwalk.NewDirective("overread", func(dec *jsontext.Decoder) (any, error) {
+	if err := dec.SkipValue(); err != nil {
+		return nil, err
+	}
+	_, err := dec.ReadToken()
+	return "read too far", err
+})
+
+func TestFailedDirectiveDrains(t *testing.T) {
+	for _, opts := range []struct {
+		name string
+		opts []jwalk.Option
+	}{
+		{"default", nil},
+		{"SentinelScan", []jwalk.Option{jwalk.WithSentinelScan()}},
+	} {
+		for _, tt := range []struct {
+			name     string
+			sentinel string
+			want     string
+		}{
+			{"partial", `{"$partial": [1, [2, 3], {"x": [4]}], "extra": {"y": 5}}`, "partial failure"},
+			{"overread", `{"$overread": [1], "extra": {"y": 5}}`, `directive "overread"`},
+		} {
+			t.Run(opts.name+"/"+tt.name, func(t *testing.T) {
+				reg, err := jwalk.NewRegistry(append(opts.opts, partial, overread)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				in := `{"a": [1, ` + tt.sentinel + `, 6], "b": 7}`
+
+				var v any
+				if err := reg.Unmarshal([]byte(in), &v); err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("Unmarshal error = %v, want %q", err, tt.want)
+				}
+
+				// the decoder is left after the sentinel, so the enclosing
+				// array and object carry on
+				dec := jsontext.NewDecoder(strings.NewReader(`[`+tt.sentinel+`, "next"]`), reg.Options()...)
+				if _, err := dec.ReadToken(); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.UnmarshalDecode(dec, &v); err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("UnmarshalDecode error = %v, want %q", err, tt.want)
+				}
+				if tok, err := dec.ReadToken(); err != nil || tok.String() != "next" {
+					t.Fatalf("after failed directive: token = %v, %v; want \"next\"", tok, err)
+				}
+
+				collecting, err := jwalk.With(reg, jwalk.WithErrorCollection())
+				if err != nil {
+					t.Fatal(err)
+				}
+				v = nil
+				err = collecting.Unmarshal([]byte(in), &v)
+				var errs jwalk.DecodeErrors
+				if !errors.As(err, &errs) || len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.want) {
+					t.Fatalf("collecting Unmarshal error = %v, want one %q", err, tt.want)
+				}
+				if got, err := reg.Marshal(v); err != nil || string(got) != `{"a":[1,null,6],"b":7}` {
+					t.Errorf("collecting Unmarshal = %s (%v), want the failed sentinel as null", got, err)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkInitialCapacity decodes objects and arrays of 32 members with and
+// without a matching WithInitialCapacity hint; the hint saves the
+// reallocations of growing each container from empty.
+func BenchmarkInitialCapacity(b *testing.B) {
+	const size = 32
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := range size {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("{")
+		for j := range size {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			fmt.Fprintf(&sb, `"k%d":%d`, j, i*size+j)
+		}
+		sb.WriteString("}")
+	}
+	sb.WriteString("]")
+	in := []byte(sb.String())
+
+	for _, bm := range []struct {
+		name string
+		opts []jwalk.Option
+	}{
+		{"NoHint", nil},
+		{"Hint", []jwalk.Option{jwalk.WithInitialCapacity(size, size)}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			reg, err := jwalk.NewRegistry(bm.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(in)))
+			b.ReportAllocs()
+			for b.Loop() {
+				var v any
+				if err := reg.Unmarshal(in, &v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSentinelScanPositions(t *testing.T) {
+	scan, err := jwalk.NewRegistry(jwalk.WithStdlib(), jwalk.WithSentinelScan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := jwalk.NewRegistry(jwalk.WithStdlib(), jwalk.WithDirectiveEncoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string // encoded without WithSentinelScan, or "" if the sentinel is dispatched either way
+	}{
+		{"first", `{"$std.duration": "1s", "b": 2}`, ""},
+		{"middle", `{"a": 1, "$std.duration": "1s", "b": 2}`, `{"a":1,"$std.duration":"1s","b":2}`},
+		{"end", `{"a": 1, "$std.duration": "1s"}`, `{"a":1,"$std.duration":"1s"}`},
+		{"middle, nested members", `{"a": {"x": [1, {"y": 2}]}, "$std.duration": "1s", "b": [[3], {"z": {}}]}`,
+			`{"a":{"x":[1,{"y":2}]},"$std.duration":"1s","b":[[3],{"z":{}}]}`},
+		{"end, nested members", `{"a": [{"$std.duration": "2s"}], "b": {"c": null}, "$std.duration": "1s"}`,
+			`{"a":[{"$std.duration":"2s"}],"b":{"c":null},"$std.duration":"1s"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the members around the sentinel are consumed, so decoding
+			// carries on with the members and elements after it
+			in := `{"v": [` + tt.in + `, "after"], "w": true}`
+
+			var v any
+			if err := scan.Unmarshal([]byte(in), &v); err != nil {
+				t.Fatal(err)
+			}
+			d := v.(jwalk.Document)
+			if got := d[0].Value.(jwalk.Array); len(got) != 2 || got[0] != time.Second || got[1] != "after" {
+				t.Errorf("WithSentinelScan: v = %#v, want [1s after]", got)
+			}
+			if len(d) != 2 || d[1].Value != true {
+				t.Errorf("WithSentinelScan: members after v = %#v", d[1:])
+			}
+
+			dec := jsontext.NewDecoder(strings.NewReader(`[`+tt.in+`, "next"]`), scan.Options()...)
+			if _, err := dec.ReadToken(); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.UnmarshalDecode(dec, &v); err != nil || v != time.Second {
+				t.Fatalf("UnmarshalDecode = %v, %v; want 1s", v, err)
+			}
+			if tok, err := dec.ReadToken(); err != nil || tok.String() != "next" {
+				t.Errorf("after sentinel: token = %v, %v; want \"next\"", tok, err)
+			}
+
+			if tt.want == "" {
+				return
+			}
+			v = nil
+			if err := plain.Unmarshal([]byte(tt.in), &v); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := plain.Marshal(v); err != nil || string(got) != tt.want {
+				t.Errorf("without WithSentinelScan = %s (%v), want %s", got, err, tt.want)
+			}
+		})
+	}
+}
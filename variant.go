@@ -0,0 +1,117 @@
+package jwalk
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// VariantDirective builds a Directive for a tagged union: an object whose
+// discriminator field selects the decode function for the rest, e.g.
+//
+//	{"$variant": {"type": "circle", "radius": 5}}
+//
+// Example:
+//
+//	d := jwalk.NewVariantDirective("variant", "type").
+//	    Case("circle", func(dec *jsontext.Decoder) (any, error) {
+//	        var c Circle
+//	        err := json.UnmarshalDecode(dec, &c)
+//	        return c, err
+//	    }).
+//	    Case("square", decodeSquare).
+//	    Directive()
+type VariantDirective struct {
+	name  string
+	field string
+	cases map[string]Unmarshaler[any]
+}
+
+// NewVariantDirective returns a VariantDirective for a directive registered
+// under name, reading the discriminator from the given field.
+func NewVariantDirective(name, field string) *VariantDirective {
+	return &VariantDirective{name: name, field: field, cases: make(map[string]Unmarshaler[any])}
+}
+
+// Case routes objects whose discriminator is typ to fn. fn is handed the
+// decoder positioned at the whole object, discriminator field included, and
+// must consume it. It panics if typ already has a case.
+func (v *VariantDirective) Case(typ string, fn Unmarshaler[any]) *VariantDirective {
+	if _, dup := v.cases[typ]; dup {
+		panic(fmt.Sprintf("jwalk: variant case %q registered twice", typ))
+	}
+	v.cases[typ] = fn
+	return v
+}
+
+// Directive returns the built Directive. A missing discriminator, or one with
+// no case, produces an error at decode time. Cases added afterwards do not
+// affect the returned Directive.
+func (v *VariantDirective) Directive() *Directive {
+	field, cases := v.field, maps.Clone(v.cases)
+	return NewRawDirective(v.name, func(dec *jsontext.Decoder) (any, error) {
+		return unmarshalVariant(dec, field, cases)
+	})
+}
+
+func unmarshalVariant(dec *jsontext.Decoder, field string, cases map[string]Unmarshaler[any]) (any, error) {
+	if k := dec.PeekKind(); k != '{' {
+		return nil, fmt.Errorf("expected object, got %s", kindName(k))
+	}
+	// The discriminator may follow the fields it governs, so buffer the
+	// object, find the discriminator, then decode the object again.
+	raw, err := dec.ReadValue()
+	if err != nil {
+		return nil, err
+	}
+	raw = raw.Clone() // only valid until the next decoder call
+
+	typ, err := variantType(raw, field)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := cases[typ]
+	if !ok {
+		known := slices.Sorted(maps.Keys(cases))
+		return nil, fmt.Errorf("unknown %s %q (expected one of %s)", field, typ, strings.Join(known, ", "))
+	}
+
+	sub, release := newSubDecoder(dec, bytes.NewReader(raw))
+	defer release()
+	val, err := fn(sub)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %w", field, typ, err)
+	}
+	return val, nil
+}
+
+// variantType returns the string value of field in the object raw.
+func variantType(raw jsontext.Value, field string) (string, error) {
+	dec := jsontext.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.ReadToken(); err != nil { // '{'
+		return "", err
+	}
+	for dec.PeekKind() != '}' {
+		name, err := dec.ReadToken()
+		if err != nil {
+			return "", err
+		}
+		if name.String() != field {
+			if err := dec.SkipValue(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		var typ string
+		if err := json.UnmarshalDecode(dec, &typ); err != nil {
+			return "", fmt.Errorf("field %q: %w", field, err)
+		}
+		return typ, nil
+	}
+	return "", fmt.Errorf("missing discriminator field %q", field)
+}
@@ -0,0 +1,183 @@
+// Package yaml bridges YAML input to jwalk's ordered Document/Array model.
+//
+// YAML mappings decode as jwalk.Document (preserving key order) and sequences
+// as jwalk.Array. Mapping keys prefixed with "$" are dispatched through the
+// Registry's directives exactly as they are for JSON input, so the same
+// directive set serves both formats.
+//
+// It is a module of its own, github.com/calumari/jwalk/yaml, so the core
+// jwalk module does not depend on gopkg.in/yaml.v3.
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/calumari/jwalk"
+	"github.com/go-json-experiment/json/jsontext"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Unmarshal decodes YAML input into out using reg's unmarshalers.
+//
+// The YAML document is first converted to equivalent JSON text, with anchors
+// and aliases resolved, and then decoded with reg.Unmarshal. Merge keys ("<<")
+// are not expanded and decode as ordinary keys. Values that have no JSON
+// representation (e.g. .inf or .nan) produce an error.
+func Unmarshal(data []byte, out any, reg *jwalk.Registry) error {
+	in, err := ToJSON(data)
+	if err != nil {
+		return err
+	}
+	return reg.Unmarshal(in, out)
+}
+
+// ToJSON converts a single YAML document into JSON text, preserving mapping
+// key order and resolving anchors and aliases. Empty input converts to null.
+// Expanding aliases may produce at most maxAliasNodes (about a million) nodes
+// in all, beyond which ToJSON fails, so that a small document of nested
+// aliases cannot expand exponentially.
+func ToJSON(data []byte) ([]byte, error) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := jsontext.NewEncoder(&buf)
+	if root.Kind == 0 { // empty document
+		if err := enc.WriteToken(jsontext.Null); err != nil {
+			return nil, err
+		}
+		return bytes.TrimSpace(buf.Bytes()), nil
+	}
+	if err := new(converter).writeNode(enc, &root, 0); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// maxAliasDepth bounds alias expansion so self-referencing anchors cannot
+// recurse forever.
+const maxAliasDepth = 100
+
+// maxAliasNodes bounds the total number of nodes emitted by expanding aliases,
+// so that a small document of nested aliases ("billion laughs") cannot expand
+// exponentially.
+const maxAliasNodes = 1 << 20
+
+// converter emits a YAML node tree as JSON tokens.
+type converter struct {
+	aliasNodes int // nodes emitted so far within alias expansions
+}
+
+// writeNode emits n, reached through the given number of aliases, as JSON
+// tokens.
+func (c *converter) writeNode(enc *jsontext.Encoder, n *yamlv3.Node, aliases int) error {
+	if aliases > 0 {
+		if c.aliasNodes++; c.aliasNodes > maxAliasNodes {
+			return fmt.Errorf("line %d: aliases expand to more than %d nodes", n.Line, maxAliasNodes)
+		}
+	}
+
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		if len(n.Content) == 0 {
+			return enc.WriteToken(jsontext.Null)
+		}
+		return c.writeNode(enc, n.Content[0], aliases)
+
+	case yamlv3.AliasNode:
+		if aliases >= maxAliasDepth {
+			return fmt.Errorf("line %d: alias %q nested too deeply", n.Line, n.Value)
+		}
+		return c.writeNode(enc, n.Alias, aliases+1)
+
+	case yamlv3.MappingNode:
+		if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, err := mappingKey(n.Content[i])
+			if err != nil {
+				return err
+			}
+			if err := enc.WriteToken(jsontext.String(key)); err != nil {
+				return fmt.Errorf("line %d: %w", n.Content[i].Line, err)
+			}
+			if err := c.writeNode(enc, n.Content[i+1], aliases); err != nil {
+				return err
+			}
+		}
+		return enc.WriteToken(jsontext.EndObject)
+
+	case yamlv3.SequenceNode:
+		if err := enc.WriteToken(jsontext.BeginArray); err != nil {
+			return err
+		}
+		for _, elem := range n.Content {
+			if err := c.writeNode(enc, elem, aliases); err != nil {
+				return err
+			}
+		}
+		return enc.WriteToken(jsontext.EndArray)
+
+	case yamlv3.ScalarNode:
+		return writeScalar(enc, n)
+
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node kind %d", n.Line, n.Kind)
+	}
+}
+
+// mappingKey returns the string form of a mapping key. Keys must be scalars
+// (or aliases of scalars); non-string scalars use their literal text.
+func mappingKey(n *yamlv3.Node) (string, error) {
+	for n.Kind == yamlv3.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yamlv3.ScalarNode {
+		return "", fmt.Errorf("line %d: mapping key must be a scalar", n.Line)
+	}
+	return n.Value, nil
+}
+
+// writeScalar emits a scalar according to its resolved YAML tag.
+func writeScalar(enc *jsontext.Encoder, n *yamlv3.Node) error {
+	switch n.ShortTag() {
+	case "!!null":
+		return enc.WriteToken(jsontext.Null)
+
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return err
+		}
+		return enc.WriteToken(jsontext.Bool(b))
+
+	case "!!int":
+		var i int64
+		if err := n.Decode(&i); err == nil {
+			return enc.WriteToken(jsontext.Int(i))
+		}
+		var u uint64
+		if err := n.Decode(&u); err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		return enc.WriteToken(jsontext.Uint(u))
+
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("line %d: %q has no JSON representation", n.Line, n.Value)
+		}
+		return enc.WriteToken(jsontext.Float(f))
+
+	default: // !!str, !!timestamp, !!binary and custom tags keep their text
+		return enc.WriteToken(jsontext.String(n.Value))
+	}
+}
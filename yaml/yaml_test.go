@@ -0,0 +1,38 @@
+package yaml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/calumari/jwalk/yaml"
+)
+
+func TestToJSONAliases(t *testing.T) {
+	in := "base: &b {x: 1, y: [2, 3]}\nuse: *b\nlist: [*b, *b]\n"
+	got, err := yaml.ToJSON([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"base":{"x":1,"y":[2,3]},"use":{"x":1,"y":[2,3]},"list":[{"x":1,"y":[2,3]},{"x":1,"y":[2,3]}]}`
+	if string(got) != want {
+		t.Errorf("ToJSON = %s, want %s", got, want)
+	}
+}
+
+func TestToJSONBillionLaughs(t *testing.T) {
+	var in strings.Builder
+	in.WriteString("a: &a [lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for i := 'b'; i <= 'i'; i++ {
+		prev := string(i - 1)
+		in.WriteString(string(i) + ": &" + string(i) + " [*" + prev)
+		for range 8 {
+			in.WriteString(", *" + prev)
+		}
+		in.WriteString("]\n")
+	}
+
+	_, err := yaml.ToJSON([]byte(in.String()))
+	if err == nil || !strings.Contains(err.Error(), "aliases expand to more than") {
+		t.Fatalf("ToJSON error = %v, want alias expansion error", err)
+	}
+}